@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func Affected() cli.Command {
+	return cli.Command{
+		Name:  "affected",
+		Usage: "lists projects changed since a git ref and every local project that depends on them",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringFlag{Name: "since, s", Usage: "git ref to compare the root and project checkouts against"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce affected requires a root meta or monorepo folder as an argument")
+			}
+
+			if c.String("since") == "" {
+				return fmt.Errorf("triforce affected requires a git ref to be given with --since")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			affected, err := affectedProjects(root, c.String("since"), c.StringSlice("exclude"), c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range affected {
+				fmt.Fprintln(c.App.Writer, name)
+			}
+
+			return nil
+		},
+	}
+}
+
+// affectedProjects finds the projects with changes since the given ref, using the project's own git
+// checkout if it has one and the root checkout otherwise, and expands them to all of their dependents
+func affectedProjects(root, since string, exclude []string, warnings io.Writer) ([]string, error) {
+	g, err := loadProjectGraph(root, []string{}, "", exclude, warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	var rootChanges []string
+	rootDiffed := false
+	var queue []string
+
+	for _, name := range g.Names() {
+		p := g.Projects[name]
+
+		if isGitCheckout(p.Path) {
+			files, err := changedFiles(p.Path, since)
+			if err != nil {
+				color.New(color.FgYellow).Fprintf(warnings, "treating %s as changed (%s)\n", name, err)
+				queue = append(queue, name)
+			} else if len(files) > 0 {
+				queue = append(queue, name)
+			}
+
+			continue
+		}
+
+		if !rootDiffed {
+			if rootChanges, err = changedFiles(root, since); err != nil {
+				return nil, err
+			}
+
+			rootDiffed = true
+		}
+
+		for _, file := range rootChanges {
+			if strings.HasPrefix(file, name+"/") {
+				queue = append(queue, name)
+				break
+			}
+		}
+	}
+
+	affected := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		if affected[name] {
+			continue
+		}
+
+		affected[name] = true
+		queue = append(queue, g.Dependents(name)...)
+	}
+
+	var names []string
+	for name := range affected {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// selectProjectFolders returns the project folders matching the filters, narrowed down to the projects
+// affected by changes since the given ref when one is given
+func selectProjectFolders(root string, filter []string, since string, exclude []string, warnings io.Writer) ([]string, error) {
+	projectFolders, err := getProjectFolders(root, filter)
+	if err != nil || since == "" {
+		return projectFolders, err
+	}
+
+	affected, err := affectedProjects(root, since, exclude, warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	isAffected := make(map[string]bool)
+	for _, name := range affected {
+		isAffected[name] = true
+	}
+
+	var selected []string
+	for _, f := range projectFolders {
+		if isAffected[f] {
+			selected = append(selected, f)
+		}
+	}
+
+	return selected, nil
+}
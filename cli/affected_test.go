@@ -0,0 +1,112 @@
+package cli_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func gitCommitAll(dir string) error {
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "--all"},
+		{"-c", "user.name=triforce", "-c", "user.email=triforce@example.com", "commit", "--quiet", "--message", "initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %s", err, out)
+		}
+	}
+
+	return nil
+}
+
+var _ = Describe("Affected", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	BeforeEach(func() {
+		p = make(map[string]*BasicPackageJSON)
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	Context("sanity checking", func() {
+		It("should throw an error if trying to find affected projects without a git ref", func() {
+			args := []string{"triforce", "affected", "."}
+			Expect(app.Run(args)).NotTo(Succeed())
+		})
+	})
+
+	Context("projects with changes since a git ref", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+			p["api-2"] = NewBasicPackageJSONBuilder().Dependency("lib-2", "github:someorg/lib-2").Build()
+			p["app-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+			p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+			p["lib-2"] = NewBasicPackageJSONBuilder().Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			// lib-2 is a separate checkout, as it would be in a meta repo
+			Expect(gitCommitAll(filepath.Join(t.RootFolder, "lib-2"))).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, ".gitignore"), []byte("lib-2\nnode_modules\n"), os.FileMode(0666))).To(Succeed())
+			Expect(gitCommitAll(t.RootFolder)).To(Succeed())
+		})
+
+		It("should list changed projects in the root checkout and their dependents", func() {
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "lib-1", "index.js"), []byte(""), os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "affected", "--since", "HEAD", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(Equal("api-1\nlib-1\n"))
+		})
+
+		It("should list changed projects in their own checkouts and their dependents", func() {
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "lib-2", "index.js"), []byte(""), os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "affected", "--since", "HEAD", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(Equal("api-2\nlib-2\n"))
+		})
+
+		It("should warn about and treat checkouts without the git ref as changed", func() {
+			var errOut bytes.Buffer
+			app.ErrWriter = &errOut
+			Expect(gitRun(t.RootFolder, "tag", "release")).To(Succeed())
+
+			args := []string{"triforce", "affected", "--since", "release", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(Equal("api-2\nlib-2\n"))
+			Expect(errOut.String()).To(ContainSubstring("treating lib-2 as changed"))
+		})
+
+		It("should only run commands in affected projects", func() {
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "lib-1", "index.js"), []byte(""), os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "exec", "--since", "HEAD", t.RootFolder, "--", "basename $PWD >> ../order.txt"}
+			Expect(app.Run(args)).To(Succeed())
+
+			order, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "order.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(order)).To(Equal("lib-1\napi-1\n"))
+		})
+	})
+})
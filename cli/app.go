@@ -5,13 +5,12 @@ import (
 
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
 
-	"path"
-
 	"github.com/Jeffail/gabs"
 	"github.com/fatih/color"
 	"github.com/urfave/cli"
@@ -42,6 +41,24 @@ func App() *cli.App {
 	app.Commands = []cli.Command{
 		Assemble(),
 		Link(),
+		Graph(),
+		Order(),
+		Exec(),
+		Run(),
+		Affected(),
+		Sync(),
+		Drift(),
+		VerifyInstall(),
+		Freeze(),
+		Lock(),
+		Snapshot(),
+		Store(),
+		Install(),
+		Checkout(),
+		Refs(),
+		Status(),
+		Prune(),
+		Unlink(),
 	}
 
 	return app
@@ -55,6 +72,13 @@ func Assemble() cli.Command {
 		Flags: []cli.Flag{
 			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
 			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "since, s", Usage: "only include projects affected by changes since this git ref"},
+			cli.StringSliceFlag{Name: "for", Usage: "only assemble dependencies reachable from these projects through local project dependencies", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "projects-file", Usage: "file to write the names of the local projects included in the assembled package.json file to"},
+			cli.BoolFlag{Name: "groups, g", Usage: "assemble a package.json file for each group defined in .triforce.json"},
+			cli.BoolFlag{Name: "split", Usage: "keep the major version required by most projects and plan project-local installs for conflicting major versions"},
+			cli.StringFlag{Name: "split-file", Usage: "file to write the project-local install plan to", Value: SplitJSON},
+			cli.BoolFlag{Name: "locked", Usage: "use the versions locked in the package-lock.json or yarn.lock file of each project when they satisfy every range"},
 		},
 		Action: func(c *cli.Context) error {
 			if c.NArg() != 1 {
@@ -68,52 +92,79 @@ func Assemble() cli.Command {
 
 			exclude := c.StringSlice("exclude")
 			filter := c.StringSlice("filter")
+			services := c.StringSlice("for")
+			name := fmt.Sprintf("triforce-%s", filepath.Base(root))
 
-			var parsedPackageJSONs []*gabs.Container
-			dependencies := make(map[string]string)
-			devDependencies := make(map[string]string)
+			// the graph has to be walked across all projects, so filters do not apply to services
+			if len(services) > 0 {
+				filter = []string{}
+				name = fmt.Sprintf("triforce-%s", strings.Join(services, "-"))
+			}
 
-			projectDirectories, err := getProjectFolders(root, filter)
+			projectDirectories, err := selectProjectFolders(root, filter, c.String("since"), exclude, c.App.ErrWriter)
 			if err != nil {
 				return err
 			}
 
-			projectDependencyMap := make(map[*gabs.Container]string)
-
-			for _, projectDirectory := range projectDirectories {
-				pkgPath := filepath.Join(root, projectDirectory, PackageJSON)
-				if _, err := os.Stat(pkgPath); err == nil {
-					parsed, err := gabs.ParseJSONFile(pkgPath)
-					if err != nil {
-						return err
-					}
+			projects, err := loadProjects(root, projectDirectories)
+			if err != nil {
+				return err
+			}
 
-					parsedPackageJSONs = append(parsedPackageJSONs, parsed)
-					projectDependencyMap[parsed] = path.Base(projectDirectory)
+			if len(services) > 0 {
+				projects, err = newProjectGraph(projects, exclude).Closure(services...)
+				if err != nil {
+					return err
 				}
 			}
 
-			for _, parsed := range parsedPackageJSONs {
-				extractDependencies(projectDependencyMap[parsed], parsed, dependencies, exclude)
+			if c.Bool("groups") {
+				if len(services) > 0 {
+					return fmt.Errorf("triforce assemble cannot assemble groups for specific projects")
+				}
+
+				for _, flag := range []string{"split", "split-file", "projects-file"} {
+					if c.IsSet(flag) {
+						return fmt.Errorf("triforce assemble cannot use --%s when assembling groups", flag)
+					}
+				}
+
+				return assembleGroups(root, projects, exclude, c.Bool("locked"))
 			}
 
-			for _, parsed := range parsedPackageJSONs {
-				extractDevDependencies(projectDependencyMap[parsed], parsed, dependencies, devDependencies, exclude)
+			t := assembleProjects(name, projects, exclude)
+			declarations := collectDeclarations(projects, exclude)
+
+			if c.Bool("split") {
+				color.Green("\nsplitting conflicting major versions")
+				plan := splitMajorVersions(&t, declarations)
+				declarations = unsplitDeclarations(declarations, plan)
+
+				bytes, err := json.MarshalIndent(plan, "", "  ")
+				if err != nil {
+					return err
+				}
+
+				if err := ioutil.WriteFile(c.String("split-file"), bytes, os.FileMode(0666)); err != nil {
+					return err
+				}
 			}
 
-			t := TriforcePackageJSON{
-				Name:            fmt.Sprintf("triforce-%s", filepath.Base(root)),
-				Description:     fmt.Sprintf("automatically generated by triforce"),
-				Dependencies:    dependencies,
-				DevDependencies: devDependencies,
+			if c.Bool("locked") {
+				color.Green("\nlocking versions from project lockfiles")
+				if err := lockVersions(&t, projects, declarations); err != nil {
+					return err
+				}
 			}
 
-			bytes, err := json.MarshalIndent(t, "", "  ")
-			if err != nil {
-				return err
+			if projectsFile := c.String("projects-file"); projectsFile != "" {
+				list := strings.Join(projectNames(projects), "\n") + "\n"
+				if err := ioutil.WriteFile(projectsFile, []byte(list), os.FileMode(0666)); err != nil {
+					return err
+				}
 			}
 
-			return ioutil.WriteFile("package.json", bytes, os.FileMode(0666))
+			return writePackageJSON(PackageJSON, t)
 		},
 	}
 }
@@ -124,7 +175,12 @@ func Link() cli.Command {
 		ShortName: "l",
 		Usage:     "links private projects inside of the node_modules folder at the meta or monorepo project root",
 		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
 			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "since, s", Usage: "only include projects affected by changes since this git ref"},
+			cli.BoolFlag{Name: "groups, g", Usage: "link the projects of each group defined in .triforce.json into the group's node_modules folder"},
+			cli.BoolFlag{Name: "strict", Usage: "fail if linked projects have private dependencies that are neither checked out nor installed"},
+			cli.StringFlag{Name: "on-collision", Usage: "what to do when a package that is not a link is installed under the name of a project: fail, skip or replace (moving it to .triforce-backup)", Value: collisionFail},
 		},
 		Action: cli.ActionFunc(func(c *cli.Context) error {
 			if c.NArg() != 1 {
@@ -138,44 +194,220 @@ func Link() cli.Command {
 
 			filter := c.StringSlice("filter")
 
-			nodeModules := filepath.Join(root, NodeModules)
-			if _, err := os.Stat(nodeModules); err != nil {
-				return fmt.Errorf("no node_modules folder found at %s", root)
+			projectFolders, err := selectProjectFolders(root, filter, c.String("since"), c.StringSlice("exclude"), c.App.ErrWriter)
+			if err != nil {
+				return err
 			}
 
-			projectFolders, err := getProjectFolders(root, filter)
+			projects, err := loadProjects(root, projectFolders)
 			if err != nil {
 				return err
 			}
 
-			for _, f := range projectFolders {
-				// if it is a node project
-				pkgPath := filepath.Join(root, f, PackageJSON)
-				if _, err := os.Stat(pkgPath); err == nil {
-					projectDirectory := path.Join("..", f)
-					symlinkDestination := filepath.Join(nodeModules, f)
+			unresolved := 0
+
+			if c.Bool("groups") {
+				config, err := loadConfig(root)
+				if err != nil {
+					return err
+				}
 
-					// remove symlinks if they already exist
-					if _, err := os.Lstat(symlinkDestination); err == nil {
-						if err := os.Remove(symlinkDestination); err != nil {
+				if len(config.Groups) == 0 {
+					return fmt.Errorf("no groups defined in %s", filepath.Join(root, TriforceConfig))
+				}
+
+				// in strict mode nothing is linked unless every group resolves its private dependencies
+				if c.Bool("strict") {
+					for _, name := range config.GroupNames() {
+						group := config.Groups[name]
+						count, err := reportUnresolved(c, root, filepath.Join(root, group.Directory), group.Select(projects))
+						if err != nil {
 							return err
 						}
+
+						unresolved += count
 					}
 
-					if err := os.Symlink(projectDirectory, symlinkDestination); err != nil {
+					if unresolved > 0 {
+						return fmt.Errorf("found %d unresolved private dependencies", unresolved)
+					}
+				}
+
+				// every group is linked in one go so that a failure in one group also undoes the links of the others
+				plan := &LinkPlan{Root: root}
+				for _, name := range config.GroupNames() {
+					group := config.Groups[name]
+					if err := plan.Add(filepath.Join(root, group.Directory), group.Select(projects), c.String("on-collision")); err != nil {
 						return err
 					}
-					fmt.Printf("symlinked %s to %s\n", f, fmt.Sprintf("./node_modules/%s", f))
 				}
-			}
 
-			color.Green("finished linking private dependencies to ./node_modules")
+				if err := plan.Apply(); err != nil {
+					return err
+				}
+
+				for _, name := range config.GroupNames() {
+					group := config.Groups[name]
+					color.New(color.FgCyan).Fprintf(c.App.Writer, "\nlinking group %s\n", name)
+
+					directory := filepath.Join(root, group.Directory)
+					plan.Print(c.App.Writer, directory)
+
+					if _, err := reportUnresolved(c, root, directory, group.Select(projects)); err != nil {
+						return err
+					}
+				}
+
+				if err := reportCollisions(c, root, plan.Collisions); err != nil {
+					return err
+				}
+			} else {
+				if c.Bool("strict") {
+					if unresolved, err = reportUnresolved(c, root, root, projects); err != nil {
+						return err
+					}
+
+					if unresolved > 0 {
+						return fmt.Errorf("found %d unresolved private dependencies", unresolved)
+					}
+				}
+
+				collisions, err := linkProjects(root, root, projects, c.String("on-collision"), c.App.Writer)
+				if err != nil {
+					return err
+				}
+
+				if err := reportCollisions(c, root, collisions); err != nil {
+					return err
+				}
+
+				if _, err := reportUnresolved(c, root, root, projects); err != nil {
+					return err
+				}
+			}
 
 			return nil
 		}),
 	}
 }
 
+// linkProjects symlinks projects into the node_modules folder of a directory, handling packages that are
+// already installed under the name of a project according to the collision policy, and lists the links to w
+func linkProjects(root, directory string, projects []*Project, onCollision string, w io.Writer) ([]*Collision, error) {
+	plan := &LinkPlan{Root: root}
+	if err := plan.Add(directory, projects, onCollision); err != nil {
+		return nil, err
+	}
+
+	if err := plan.Apply(); err != nil {
+		return nil, err
+	}
+
+	plan.Print(w, directory)
+	return plan.Collisions, nil
+}
+
+func assembleProjects(name string, projects []*Project, exclude []string) TriforcePackageJSON {
+	dependencies := make(map[string]string)
+	devDependencies := make(map[string]string)
+
+	for _, p := range projects {
+		extractDependencies(p.Name, p.Parsed, dependencies, exclude)
+	}
+
+	for _, p := range projects {
+		extractDevDependencies(p.Name, p.Parsed, dependencies, devDependencies, exclude)
+	}
+
+	return TriforcePackageJSON{
+		Name:            name,
+		Description:     fmt.Sprintf("automatically generated by triforce"),
+		Dependencies:    dependencies,
+		DevDependencies: devDependencies,
+	}
+}
+
+func assembleGroups(root string, projects []*Project, exclude []string, locked bool) error {
+	targets, err := groupTargets(root, projects)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		color.Cyan("\nassembling group %s", target.Group)
+
+		if err := os.MkdirAll(target.Directory, os.FileMode(0755)); err != nil {
+			return err
+		}
+
+		t, err := assembleManifest(target.Name, target.Projects, exclude, locked)
+		if err != nil {
+			return err
+		}
+
+		if err := writePackageJSON(filepath.Join(target.Directory, PackageJSON), t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// manifestTarget is an assembled package.json file, the folder it is written to and the projects it is
+// assembled from, which are also linked into the node_modules folder next to it
+type manifestTarget struct {
+	Group     string
+	Name      string
+	Directory string
+	Projects  []*Project
+}
+
+// groupTargets returns a target in the directory of each group defined in .triforce.json
+func groupTargets(root string, projects []*Project) ([]manifestTarget, error) {
+	config, err := loadConfig(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.Groups) == 0 {
+		return nil, fmt.Errorf("no groups defined in %s", filepath.Join(root, TriforceConfig))
+	}
+
+	var targets []manifestTarget
+	for _, name := range config.GroupNames() {
+		group := config.Groups[name]
+		targets = append(targets, manifestTarget{
+			Group:     name,
+			Name:      fmt.Sprintf("triforce-%s", name),
+			Directory: filepath.Join(root, group.Directory),
+			Projects:  group.Select(projects),
+		})
+	}
+
+	return targets, nil
+}
+
+func assembleManifest(name string, projects []*Project, exclude []string, locked bool) (TriforcePackageJSON, error) {
+	t := assembleProjects(name, projects, exclude)
+	if locked {
+		color.Green("\nlocking versions from project lockfiles")
+		if err := lockVersions(&t, projects, collectDeclarations(projects, exclude)); err != nil {
+			return t, err
+		}
+	}
+
+	return t, nil
+}
+
+func writePackageJSON(file string, t TriforcePackageJSON) error {
+	bytes, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, bytes, os.FileMode(0666))
+}
+
 func getProjectFolders(root string, filters []string) ([]string, error) {
 	var projectDirectories []string
 	dirs, err := ioutil.ReadDir(root)
@@ -183,11 +415,22 @@ func getProjectFolders(root string, filters []string) ([]string, error) {
 		return nil, err
 	}
 
+	config, err := loadConfig(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// groups are assembled into their own folders at the root, which are not projects themselves
+	groupDirectories := make(map[string]bool)
+	for _, group := range config.Groups {
+		groupDirectories[filepath.Clean(group.Directory)] = true
+	}
+
 	hasFilterPatterns := len(filters) > 0
 
 	for _, d := range dirs {
-		// ignore non-directories and hidden files
-		if d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+		// ignore non-directories, hidden files and group folders
+		if d.IsDir() && !strings.HasPrefix(d.Name(), ".") && !groupDirectories[d.Name()] {
 			if hasFilterPatterns {
 				for _, filter := range filters {
 					if strings.Contains(d.Name(), filter) {
@@ -228,7 +471,7 @@ func extractDependencies(project string, parsed *gabs.Container, dependencies ma
 
 			// Update in dependencies if it is a greater version
 			if val, ok := dependencies[dep]; ok {
-				if shouldUpdate(val, version.(string)) {
+				if isHigherRange(val, version.(string)) {
 					dependencies[dep] = version.(string)
 					fmt.Println(updated("dependency", dep, val, version.(string)))
 					continue
@@ -258,7 +501,7 @@ func extractDevDependencies(project string, parsed *gabs.Container, dependencies
 
 			// Update in dependencies if it is a greater version
 			if val, ok := dependencies[devDep]; ok {
-				if shouldUpdate(val, version.(string)) {
+				if isHigherRange(val, version.(string)) {
 					dependencies[devDep] = version.(string)
 					fmt.Println(promoted(devDep, val, version.(string)))
 					continue
@@ -269,7 +512,7 @@ func extractDevDependencies(project string, parsed *gabs.Container, dependencies
 
 			// Otherwise update in devDependencies if the version is greater
 			if val, ok := devDependencies[devDep]; ok {
-				if shouldUpdate(val, version.(string)) {
+				if isHigherRange(val, version.(string)) {
 					devDependencies[devDep] = version.(string)
 					fmt.Println(updated("devDependency", devDep, val, version.(string)))
 					continue
@@ -314,3 +557,19 @@ func updated(depType, name, lowerVersion, higherVersion string) string {
 func promoted(name, lowerVersion, higherVersion string) string {
 	return fmt.Sprintf("promoted devDependency \"%s\" to replace previously added dependency with higher version (\"%s\" > \"%s\")", name, higherVersion, lowerVersion)
 }
+
+func locked(depType, name, version string, projects []string) string {
+	return fmt.Sprintf("locked %s \"%s\" to version \"%s\" (locked in %s)", depType, name, version, strings.Join(projects, ", "))
+}
+
+func unlocked(depType, name, version string) string {
+	return fmt.Sprintf("kept %s \"%s\" with version \"%s\" (no locked version satisfies every declared range)", depType, name, version)
+}
+
+func ignoredRange(depType, name, version, project string) string {
+	return fmt.Sprintf("ignored %s \"%s\" with version \"%s\" in %s when locking (not a semver range)", depType, name, version, project)
+}
+
+func split(depType, name, version, project, majorityVersion string) string {
+	return fmt.Sprintf("split %s \"%s\" with version \"%s\" into ./%s/node_modules (conflicts with major version of \"%s\" required by most projects)", depType, name, version, project, majorityVersion)
+}
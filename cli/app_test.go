@@ -1,6 +1,7 @@
 package cli_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"os"
@@ -15,9 +16,16 @@ import (
 
 type BasicPackageJSON struct {
 	Name            string            `json:"name"`
+	Version         string            `json:"version,omitempty"`
 	Description     string            `json:"description"`
 	Dependencies    map[string]string `json:"dependencies"`
 	DevDependencies map[string]string `json:"devDependencies"`
+	Scripts         map[string]string `json:"scripts,omitempty"`
+	Triforce        *TriforceSection  `json:"triforce,omitempty"`
+}
+
+type TriforceSection struct {
+	Tags []string `json:"tags"`
 }
 
 type TestSpace struct {
@@ -31,8 +39,10 @@ func (t *TestSpace) Destroy() error {
 			return err
 		}
 
-		if err := os.RemoveAll("package.json"); err != nil {
-			return err
+		for _, file := range []string{"package.json", "triforce-split.json"} {
+			if err := os.RemoveAll(file); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -63,6 +73,57 @@ func NewTestSpace(projects map[string]*BasicPackageJSON) (*TestSpace, error) {
 	return t, nil
 }
 
+// Install writes a package into the node_modules folder at the root of the test space
+func (t *TestSpace) Install(name, version string, dependencies ...string) error {
+	return t.install(t.RootFolder, name, version, dependencies)
+}
+
+// InstallInProject writes a package into the node_modules folder of a project in the test space
+func (t *TestSpace) InstallInProject(project, name, version string) error {
+	return t.install(filepath.Join(t.RootFolder, project), name, version, nil)
+}
+
+func (t *TestSpace) install(directory, name, version string, dependencies []string) error {
+	dir := filepath.Join(directory, "node_modules", filepath.FromSlash(name))
+	if err := os.MkdirAll(dir, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	pkg := NewBasicPackageJSONBuilder().Name(name).Build()
+	pkg.Version = version
+	for _, dep := range dependencies {
+		pkg.Dependencies[dep] = "*"
+	}
+
+	bytes, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "package.json"), bytes, os.FileMode(0666))
+}
+
+// WriteManifest writes an assembled package.json file to the root of the test space
+func (t *TestSpace) WriteManifest(manifest *BasicPackageJSON) error {
+	bytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(t.RootFolder, "package.json"), bytes, os.FileMode(0666))
+}
+
+func (t *TestSpace) WriteConfig(config string) error {
+	return ioutil.WriteFile(filepath.Join(t.RootFolder, ".triforce.json"), []byte(config), os.FileMode(0666))
+}
+
+const groupsConfig = `{
+  "groups": {
+    "apis": {"patterns": ["api-*"]},
+    "frontend": {"tags": ["frontend"], "directory": "apps"}
+  }
+}`
+
 var _ = Describe("Assemble", func() {
 	var p map[string]*BasicPackageJSON
 	var t *TestSpace
@@ -284,6 +345,34 @@ var _ = Describe("Assemble", func() {
 				Expect(pkg.Dependencies).To(HaveKeyWithValue("dep-a", "1.0.0"))
 			})
 		})
+
+		It("should compare versions numerically and select the same version as sync", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^9.0.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^10.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "assemble", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+			Expect(pkg.Dependencies).To(HaveKeyWithValue("dep-a", "^10.0.0"))
+
+			By("syncing the projects to the assembled version", func() {
+				args := []string{"triforce", "sync", t.RootFolder}
+				Expect(cli.App().Run(args)).To(Succeed())
+
+				bytes, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "project-1", "package.json"))
+				Expect(err).NotTo(HaveOccurred())
+				project := BasicPackageJSON{}
+				Expect(json.Unmarshal(bytes, &project)).To(Succeed())
+				Expect(project.Dependencies).To(HaveKeyWithValue("dep-a", pkg.Dependencies["dep-a"]))
+			})
+		})
 	})
 
 	Context("projects with overlapping devDependencies", func() {
@@ -405,6 +494,320 @@ var _ = Describe("Assemble", func() {
 
 		})
 	})
+	Context("assembling for a single service", func() {
+		It("should only contain dependencies reachable from the given project through local projects", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().
+				Dependency("dep-a", "1.0.0").
+				Dependency("lib-1", "github:someorg/lib-1#v1.0.0").
+				Build()
+
+			p["lib-1"] = NewBasicPackageJSONBuilder().
+				Dependency("dep-b", "1.0.0").
+				Dependency("lib-2", "^1.0.0").
+				Build()
+
+			p["lib-2"] = NewBasicPackageJSONBuilder().
+				DevDependency("devdep-c", "1.0.0").
+				Build()
+
+			p["api-2"] = NewBasicPackageJSONBuilder().
+				Dependency("dep-d", "1.0.0").
+				Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			projectsFile := filepath.Join(t.RootFolder, "projects.txt")
+			args := []string{"triforce", "assemble", "--for", "api-1", "--projects-file", projectsFile, t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+			Expect("package.json").To(BeAnExistingFile())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+
+			Expect(pkg.Name).To(Equal("triforce-api-1"))
+			Expect(pkg.Dependencies).To(HaveKey("dep-a"))
+			Expect(pkg.Dependencies).To(HaveKey("dep-b"))
+			Expect(pkg.Dependencies).To(HaveKey("lib-2"))
+			Expect(pkg.Dependencies).NotTo(HaveKey("dep-d"))
+			Expect(pkg.DevDependencies).To(HaveKey("devdep-c"))
+
+			By("listing the local projects that need to be copied for the service", func() {
+				list, err := ioutil.ReadFile(projectsFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(list)).To(Equal("api-1\nlib-1\nlib-2\n"))
+			})
+		})
+
+		It("should throw an error if the given project does not exist", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "assemble", "--for", "api-2", t.RootFolder}
+			Expect(cli.App().Run(args)).NotTo(Succeed())
+		})
+	})
+	Context("projects with conflicting major versions assembled in split mode", func() {
+		It("should keep the major version required by most projects and plan project-local installs for the others", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.1.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.2.0").Build()
+			p["project-3"] = NewBasicPackageJSONBuilder().DevDependency("dep-a", "^5.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "assemble", "--split", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+
+			Expect(pkg.Dependencies).To(HaveKeyWithValue("dep-a", "^4.2.0"))
+
+			By("writing the conflicting versions to the split plan", func() {
+				bytes, err := ioutil.ReadFile("triforce-split.json")
+				Expect(err).NotTo(HaveOccurred())
+				plan := make(map[string]*BasicPackageJSON)
+				Expect(json.Unmarshal(bytes, &plan)).To(Succeed())
+
+				Expect(plan).To(HaveLen(1))
+				Expect(plan).To(HaveKey("project-3"))
+				Expect(plan["project-3"].DevDependencies).To(HaveKeyWithValue("dep-a", "^5.0.0"))
+			})
+		})
+
+		It("should prefer the higher major version when the same number of projects require each", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "~1.0.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "2.x").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "assemble", "--split", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("triforce-split.json")
+			Expect(err).NotTo(HaveOccurred())
+			plan := make(map[string]*BasicPackageJSON)
+			Expect(json.Unmarshal(bytes, &plan)).To(Succeed())
+
+			Expect(plan).To(HaveKey("project-1"))
+			Expect(plan["project-1"].Dependencies).To(HaveKeyWithValue("dep-a", "~1.0.0"))
+		})
+
+		It("should compare versions numerically when keeping the version required by most projects", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.10.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.9.0").Build()
+			p["project-3"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^2.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "assemble", "--split", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+
+			Expect(pkg.Dependencies).To(HaveKeyWithValue("dep-a", "^1.10.0"))
+		})
+	})
+
+	Context("projects with lockfiles assembled in locked mode", func() {
+		It("should use the highest locked version that satisfies every declared range", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.2.0").DevDependency("dep-b", "~1.0.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.1.0").Build()
+			p["project-3"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "project-1", "package-lock.json"), []byte(`{
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "project-1"},
+    "node_modules/dep-a": {"version": "4.2.1"},
+    "node_modules/dep-b": {"version": "1.0.4", "dev": true}
+  }
+}`), os.FileMode(0666))).To(Succeed())
+
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "project-2", "yarn.lock"), []byte(`# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"dep-a@^4.1.0":
+  version "4.3.0"
+  resolved "https://registry.yarnpkg.com/dep-a/-/dep-a-4.3.0.tgz"
+`), os.FileMode(0666))).To(Succeed())
+
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "project-3", "package-lock.json"), []byte(`{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "dep-a": {"version": "4.0.2"}
+  }
+}`), os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "assemble", "--locked", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+
+			Expect(pkg.Dependencies).To(Equal(map[string]string{"dep-a": "4.3.0"}))
+			Expect(pkg.DevDependencies).To(Equal(map[string]string{"dep-b": "1.0.4"}))
+		})
+
+		It("should keep the assembled range if no locked version satisfies every declared range", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.1.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.2.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "project-1", "package-lock.json"), []byte(`{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "dep-a": {"version": "4.1.3"}
+  }
+}`), os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "assemble", "--locked", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+
+			Expect(pkg.Dependencies).To(Equal(map[string]string{"dep-a": "^4.2.0"}))
+		})
+
+		It("should ignore declared ranges that cannot be compared with locked versions", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^4.1.0").Build()
+			p["project-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "latest").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "project-1", "package-lock.json"), []byte(`{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "dep-a": {"version": "4.1.3"}
+  }
+}`), os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "assemble", "--locked", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+
+			Expect(pkg.Dependencies).To(Equal(map[string]string{"dep-a": "4.1.3"}))
+		})
+	})
+
+	Context("projects assembled in groups", func() {
+		It("should assemble a package.json file for each group defined in .triforce.json", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+			p["api-2"] = NewBasicPackageJSONBuilder().Dependency("dep-b", "1.0.0").Build()
+			p["app-1"] = NewBasicPackageJSONBuilder().Dependency("dep-c", "2.0.0").Tag("frontend").Build()
+			p["lib-1"] = NewBasicPackageJSONBuilder().Dependency("dep-d", "1.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.WriteConfig(groupsConfig)).To(Succeed())
+
+			args := []string{"triforce", "assemble", "--groups", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "apis", "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+			apis := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &apis)).To(Succeed())
+
+			Expect(apis.Name).To(Equal("triforce-apis"))
+			Expect(apis.Dependencies).To(Equal(map[string]string{"dep-a": "1.0.0", "dep-b": "1.0.0"}))
+
+			bytes, err = ioutil.ReadFile(filepath.Join(t.RootFolder, "apps", "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+			frontend := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &frontend)).To(Succeed())
+
+			Expect(frontend.Name).To(Equal("triforce-frontend"))
+			Expect(frontend.Dependencies).To(Equal(map[string]string{"dep-c": "2.0.0"}))
+		})
+
+		It("should not treat the folders assembled for groups as projects", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+			p["app-1"] = NewBasicPackageJSONBuilder().Dependency("dep-c", "2.0.0").Tag("frontend").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.WriteConfig(groupsConfig)).To(Succeed())
+
+			args := []string{"triforce", "assemble", "--groups", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+			Expect(filepath.Join(t.RootFolder, "apis", "package.json")).To(BeAnExistingFile())
+
+			args = []string{"triforce", "assemble", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile("package.json")
+			Expect(err).NotTo(HaveOccurred())
+			pkg := BasicPackageJSON{}
+			Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+			Expect(pkg.Dependencies).To(Equal(map[string]string{"dep-a": "1.0.0", "dep-c": "2.0.0"}))
+
+			args = []string{"triforce", "link", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			for _, project := range []string{"api-1", "app-1"} {
+				Expect(filepath.Join(t.RootFolder, "node_modules", project)).To(BeADirectory())
+			}
+
+			for _, group := range []string{"apis", "apps"} {
+				_, err := os.Lstat(filepath.Join(t.RootFolder, "node_modules", group))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			}
+		})
+
+		It("should throw an error if groups are assembled with flags that only apply to a single package.json file", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.WriteConfig(groupsConfig)).To(Succeed())
+
+			for _, flags := range [][]string{{"--split"}, {"--split-file", "split.json"}, {"--projects-file", "projects.txt"}} {
+				args := append(append([]string{"triforce", "assemble", "--groups"}, flags...), t.RootFolder)
+				Expect(cli.App().Run(args)).NotTo(Succeed())
+			}
+
+			Expect(filepath.Join(t.RootFolder, "apis", "package.json")).NotTo(BeAnExistingFile())
+		})
+
+		It("should throw an error if no groups are defined", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "assemble", "--groups", t.RootFolder}
+			Expect(cli.App().Run(args)).NotTo(Succeed())
+		})
+	})
 })
 
 var _ = Describe("Link", func() {
@@ -499,6 +902,163 @@ var _ = Describe("Link", func() {
 			}
 		})
 	})
+	Context("projects with private dependencies that are not checked out", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().
+				Dependency("lib-1", "github:someorg/lib-1").
+				Dependency("lib-2", "github:someorg/lib-2#v1.0.0").
+				Dependency("lib-3", "github:someorg/lib-3").
+				Build()
+
+			p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Install("lib-3", "1.0.0")).To(Succeed())
+		})
+
+		It("should report the private dependencies that are neither local projects nor installed", func() {
+			var out bytes.Buffer
+			app := cli.App()
+			app.Writer = &out
+
+			args := []string{"triforce", "link", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			Expect(out.String()).To(ContainSubstring("symlinked lib-1 to "))
+			Expect(out.String()).To(ContainSubstring("finished linking private dependencies to "))
+			Expect(out.String()).To(ContainSubstring("private dependencies that are neither checked out nor installed in ./node_modules"))
+			Expect(out.String()).To(MatchRegexp(`api-1\s+lib-2\s+github:someorg/lib-2#v1.0.0`))
+			Expect(out.String()).NotTo(ContainSubstring("lib-1  "))
+			Expect(out.String()).NotTo(ContainSubstring("lib-3"))
+		})
+
+		It("should throw an error in strict mode", func() {
+			args := []string{"triforce", "link", "--strict", t.RootFolder}
+			Expect(cli.App().Run(args)).NotTo(Succeed())
+
+			By("not linking any of the projects", func() {
+				for _, project := range []string{"api-1", "lib-1"} {
+					_, err := os.Lstat(filepath.Join(t.RootFolder, "node_modules", project))
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				}
+			})
+		})
+	})
+
+	Context("projects that are already installed as packages", func() {
+		var out bytes.Buffer
+		var app = cli.App()
+
+		BeforeEach(func() {
+			out.Reset()
+			app = cli.App()
+			app.Writer = &out
+
+			p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+			p["lib-2"] = NewBasicPackageJSONBuilder().Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Install("lib-1", "1.0.0")).To(Succeed())
+		})
+
+		It("should throw an error without linking anything by default", func() {
+			args := []string{"triforce", "link", t.RootFolder}
+			Expect(app.Run(args)).To(MatchError("found packages that are not links in ./node_modules: lib-1 (use --on-collision=skip or --on-collision=replace)"))
+
+			Expect(filepath.Join(t.RootFolder, "node_modules", "lib-1", "package.json")).To(BeAnExistingFile())
+			Expect(filepath.Join(t.RootFolder, "node_modules", "lib-2")).NotTo(BeAnExistingFile())
+		})
+
+		It("should leave installed packages in place when skipping", func() {
+			args := []string{"triforce", "link", "--on-collision", "skip", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			Expect(filepath.Join(t.RootFolder, "node_modules", "lib-1", "package.json")).To(BeAnExistingFile())
+			_, err := os.Readlink(filepath.Join(t.RootFolder, "node_modules", "lib-2"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(MatchRegexp(`lib-1\s+\./node_modules/lib-1\s+skipped`))
+		})
+
+		It("should back up installed packages when replacing", func() {
+			args := []string{"triforce", "link", "--on-collision", "replace", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			symlinkOrigin, err := os.Readlink(filepath.Join(t.RootFolder, "node_modules", "lib-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(symlinkOrigin).To(Equal("../lib-1"))
+			Expect(filepath.Join(t.RootFolder, ".triforce-backup", "lib-1", "package.json")).To(BeAnExistingFile())
+
+			Expect(out.String()).To(MatchRegexp(`lib-1\s+\./node_modules/lib-1\s+replaced \(backed up to \./\.triforce-backup/lib-1\)`))
+		})
+
+		It("should throw an error for an unknown collision policy", func() {
+			args := []string{"triforce", "link", "--on-collision", "merge", t.RootFolder}
+			Expect(app.Run(args)).To(MatchError("unknown collision policy \"merge\" (use fail, skip or replace)"))
+		})
+	})
+
+	Context("projects linked in groups", func() {
+		It("should link the projects of each group into the group's node_modules folder", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+			p["app-1"] = NewBasicPackageJSONBuilder().Dependency("dep-b", "1.0.0").Tag("frontend").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.WriteConfig(groupsConfig)).To(Succeed())
+
+			for _, directory := range []string{"apis", "apps"} {
+				Expect(os.MkdirAll(filepath.Join(t.RootFolder, directory, "node_modules"), os.FileMode(0700))).To(Succeed())
+			}
+
+			args := []string{"triforce", "link", "--groups", t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			symlinkOrigin, err := os.Readlink(filepath.Join(t.RootFolder, "apis", "node_modules", "api-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(symlinkOrigin).To(Equal("../../api-1"))
+			Expect(filepath.Join(t.RootFolder, "apis", "node_modules", "api-1")).To(BeADirectory())
+			Expect(filepath.Join(t.RootFolder, "apis", "node_modules", "app-1")).NotTo(BeAnExistingFile())
+
+			symlinkOrigin, err = os.Readlink(filepath.Join(t.RootFolder, "apps", "node_modules", "app-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(symlinkOrigin).To(Equal("../../app-1"))
+		})
+
+		It("should roll back every link in every group if linking a project fails", func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Build()
+			p["api-2"] = NewBasicPackageJSONBuilder().Build()
+			p["app-1"] = NewBasicPackageJSONBuilder().Tag("frontend").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.WriteConfig(groupsConfig)).To(Succeed())
+
+			// api-1 has a stale link and api-2 is installed as a package, while apps has no usable node_modules folder
+			apis := filepath.Join(t.RootFolder, "apis", "node_modules")
+			Expect(os.MkdirAll(filepath.Join(apis, "api-2"), os.FileMode(0700))).To(Succeed())
+			Expect(os.Symlink("../../old-api-1", filepath.Join(apis, "api-1"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(t.RootFolder, "apps"), os.FileMode(0700))).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "apps", "node_modules"), []byte{}, os.FileMode(0666))).To(Succeed())
+
+			args := []string{"triforce", "link", "--groups", "--on-collision", "replace", t.RootFolder}
+			err := cli.App().Run(args)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("could not link app-1 into ./apps/node_modules"))
+			Expect(err.Error()).To(HaveSuffix("rolled back all changes"))
+
+			symlinkOrigin, err := os.Readlink(filepath.Join(apis, "api-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(symlinkOrigin).To(Equal("../../old-api-1"))
+
+			info, err := os.Lstat(filepath.Join(apis, "api-2"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(filepath.Join(t.RootFolder, "apis", ".triforce-backup")).NotTo(BeAnExistingFile())
+		})
+	})
 })
 
 type BasicPackageJSONBuilder struct {
@@ -511,6 +1071,11 @@ func NewBasicPackageJSONBuilder() *BasicPackageJSONBuilder {
 	return b
 }
 
+func (b *BasicPackageJSONBuilder) Name(name string) *BasicPackageJSONBuilder {
+	b.basicPackageJSON.Name = name
+	return b
+}
+
 func (b *BasicPackageJSONBuilder) Dependency(dependency, version string) *BasicPackageJSONBuilder {
 	b.basicPackageJSON.Dependencies[dependency] = version
 	return b
@@ -521,6 +1086,24 @@ func (b *BasicPackageJSONBuilder) DevDependency(devDependency, version string) *
 	return b
 }
 
+func (b *BasicPackageJSONBuilder) Script(name, command string) *BasicPackageJSONBuilder {
+	if b.basicPackageJSON.Scripts == nil {
+		b.basicPackageJSON.Scripts = make(map[string]string)
+	}
+
+	b.basicPackageJSON.Scripts[name] = command
+	return b
+}
+
+func (b *BasicPackageJSONBuilder) Tag(tag string) *BasicPackageJSONBuilder {
+	if b.basicPackageJSON.Triforce == nil {
+		b.basicPackageJSON.Triforce = &TriforceSection{}
+	}
+
+	b.basicPackageJSON.Triforce.Tags = append(b.basicPackageJSON.Triforce.Tags, tag)
+	return b
+}
+
 func (b *BasicPackageJSONBuilder) Build() *BasicPackageJSON {
 	return b.basicPackageJSON
 }
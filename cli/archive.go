@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeArchive writes a gzipped tar of a folder to w with paths relative to the parent of the folder,
+// preserving symlinks and permissions and leaving out any path for which skip returns true
+func writeArchive(w io.Writer, folder string, skip func(path string, info os.FileInfo) bool) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	parent := filepath.Dir(folder)
+
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if skip != nil && skip(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tw, file)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// readArchive calls fn with the path and header of every entry of a gzipped tar written by writeArchive,
+// leaving out any entry for which skip returns true and refusing entries with paths that would end up
+// outside of the folder the archive is read into, either directly or through a symlink in the archive
+func readArchive(r io.Reader, skip func(header *tar.Header) bool, fn func(name string, header *tar.Header, content io.Reader) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	links := make(archiveLinks)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if skip != nil && skip(header) {
+			continue
+		}
+
+		name, err := archivePath(header.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := links.check(name); err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeSymlink {
+			if err := links.add(name, header.Linkname); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(name, header, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// archivePath turns the name of an archive entry into a relative path, refusing names that are absolute or
+// that climb out of the folder the archive is read into
+func archivePath(entry string) (string, error) {
+	name := filepath.Clean(filepath.FromSlash(strings.TrimSuffix(entry, "/")))
+	if filepath.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to read %s outside of the archive", entry)
+	}
+
+	return name, nil
+}
+
+// archiveLinks tracks the symlinks read from an archive, so that no later entry is written through one of
+// them and no symlink points outside of the top level folder of the archive
+type archiveLinks map[string]bool
+
+// check refuses a path that is, or is inside of, a symlink read earlier
+func (links archiveLinks) check(name string) error {
+	for path := name; path != "." && path != string(filepath.Separator); path = filepath.Dir(path) {
+		if links[path] {
+			return fmt.Errorf("refusing to write %s through the symlink %s", filepath.ToSlash(name), filepath.ToSlash(path))
+		}
+	}
+
+	return nil
+}
+
+// add records a symlink, refusing targets that are absolute, that resolve outside of the top level folder of
+// the archive, or that climb back up after descending, since the folders they descend into may themselves be
+// symlinks and make the target resolve somewhere else than it appears to
+func (links archiveLinks) add(name, target string) error {
+	refuse := fmt.Errorf("refusing the symlink %s to %s outside of the archive", filepath.ToSlash(name), target)
+
+	target = filepath.FromSlash(target)
+	if target == "" || filepath.IsAbs(target) {
+		return refuse
+	}
+
+	descended := false
+	for _, part := range strings.Split(target, string(filepath.Separator)) {
+		switch part {
+		case "", ".":
+		case "..":
+			if descended {
+				return refuse
+			}
+		default:
+			descended = true
+		}
+	}
+
+	top := strings.SplitN(name, string(filepath.Separator), 2)[0]
+	resolved := filepath.Join(filepath.Dir(name), target)
+	if resolved != top && !strings.HasPrefix(resolved, top+string(filepath.Separator)) {
+		return refuse
+	}
+
+	links[name] = true
+	return nil
+}
+
+// extractArchive extracts a gzipped tar written by writeArchive into the destination folder, leaving out
+// any entry for which skip returns true
+func extractArchive(r io.Reader, destination string, skip func(header *tar.Header) bool) error {
+	var folders folderModes
+	err := readArchive(r, skip, func(name string, header *tar.Header, content io.Reader) error {
+		path := filepath.Join(destination, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			return err
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.FileMode(0755)); err != nil {
+				return err
+			}
+
+			folders.add(path, mode)
+		case tar.TypeSymlink:
+			return os.Symlink(header.Linkname, path)
+		case tar.TypeReg:
+			return writeFile(path, content, mode)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return folders.apply()
+}
+
+// folderModes records the permissions of folders as they are created, to be applied once everything has been
+// written into them since nothing could be written into a folder that is archived as read-only otherwise
+type folderModes []folderMode
+
+type folderMode struct {
+	Path string
+	Mode os.FileMode
+}
+
+func (m *folderModes) add(path string, mode os.FileMode) {
+	*m = append(*m, folderMode{Path: path, Mode: mode})
+}
+
+// apply sets the permissions of the deepest folders first, so that a parent folder without search permission
+// does not stop its children from being changed
+func (m folderModes) apply() error {
+	for i := len(m) - 1; i >= 0; i-- {
+		if err := os.Chmod(m[i].Path, m[i].Mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeFile(path string, content io.Reader, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotBackend stores snapshots and their checksums by name
+type SnapshotBackend interface {
+	// Put stores the contents of a local file under the given name
+	Put(name, file string) error
+	// Open reads the object stored under the given name starting from an offset, reporting whether the
+	// backend honoured the offset or is reading from the start of the object
+	Open(name string, offset int64) (io.ReadCloser, bool, error)
+	String() string
+}
+
+// newSnapshotBackend returns the backend for a storage URL, which can be an http, https or s3 URL, or
+// otherwise the path of a local directory
+func newSnapshotBackend(location string, s3Config S3Config) (SnapshotBackend, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		if err == nil && u.Scheme == "file" {
+			location = u.Path
+		}
+
+		return &LocalBackend{Directory: location}, nil
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &HTTPBackend{URL: strings.TrimSuffix(location, "/"), Client: http.DefaultClient}, nil
+	case "s3":
+		return newS3Backend(u.Host, strings.Trim(u.Path, "/"), s3Config)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot storage %s", location)
+	}
+}
+
+type LocalBackend struct {
+	Directory string
+}
+
+func (b *LocalBackend) String() string {
+	return b.Directory
+}
+
+func (b *LocalBackend) Put(name, file string) error {
+	if err := os.MkdirAll(b.Directory, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	source, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// copy to a temporary file first so that an interrupted copy never leaves a broken snapshot behind
+	tmp, err := ioutil.TempFile(b.Directory, ".snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, source); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(b.Directory, name))
+}
+
+func (b *LocalBackend) Open(name string, offset int64) (io.ReadCloser, bool, error) {
+	file, err := os.Open(filepath.Join(b.Directory, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, errSnapshotNotFound
+		}
+
+		return nil, false, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, false, err
+	}
+
+	return file, true, nil
+}
+
+// HTTPBackend stores snapshots with plain GET and PUT requests below a base URL, using basic auth if the
+// URL contains credentials
+type HTTPBackend struct {
+	URL    string
+	Client *http.Client
+}
+
+func (b *HTTPBackend) String() string {
+	u, err := url.Parse(b.URL)
+	if err != nil {
+		return b.URL
+	}
+
+	u.User = nil
+	return u.String()
+}
+
+func (b *HTTPBackend) Put(name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, b.URL+"/"+name, f)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = info.Size()
+	res, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("could not upload %s to %s: %s", name, b, res.Status)
+	}
+
+	return nil
+}
+
+func (b *HTTPBackend) Open(name string, offset int64) (io.ReadCloser, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, b.URL+"/"+name, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return openRange(b.Client, req, offset)
+}
+
+// openRange sends a GET request for the part of an object after an offset, falling back to the whole object
+// if the server does not support ranges
+func openRange(client *http.Client, req *http.Request, offset int64) (io.ReadCloser, bool, error) {
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, offset == 0, nil
+	case http.StatusPartialContent:
+		return res.Body, true, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial download is already complete
+		res.Body.Close()
+		return ioutil.NopCloser(&bytes.Buffer{}), true, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, false, errSnapshotNotFound
+	default:
+		res.Body.Close()
+		return nil, false, &statusError{Path: req.URL.Path, Status: res.Status, Code: res.StatusCode}
+	}
+}
+
+// statusError is a response to a download that is neither the object nor a sign that it does not exist
+type statusError struct {
+	Path   string
+	Status string
+	Code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("could not download %s: %s", e.Path, e.Status)
+}
+
+// uploadSnapshot stores a snapshot along with a sha256 checksum that is verified when it is downloaded
+func uploadSnapshot(backend SnapshotBackend, key, file string) error {
+	checksum, err := fileChecksum(file)
+	if err != nil {
+		return err
+	}
+
+	sum, err := ioutil.TempFile("", "triforce-checksum")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sum.Name())
+
+	if _, err := sum.WriteString(checksum + "\n"); err != nil {
+		sum.Close()
+		return err
+	}
+
+	if err := sum.Close(); err != nil {
+		return err
+	}
+
+	if err := backend.Put(key+SnapshotExtension, file); err != nil {
+		return err
+	}
+
+	// the checksum is written last so that a snapshot is only ever found once it has been fully uploaded
+	return backend.Put(key+SnapshotExtension+ChecksumExtension, sum.Name())
+}
+
+// downloadSnapshot fetches a snapshot into the given file, resuming from a partial download left behind by
+// an earlier attempt, and verifies it against its checksum
+func downloadSnapshot(backend SnapshotBackend, key, file string) error {
+	expected, err := readObject(backend, key+SnapshotExtension+ChecksumExtension)
+	if err != nil {
+		return err
+	}
+
+	partial := file + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	body, resumed, err := backend.Open(key+SnapshotExtension, offset)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resumed {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+
+	out, err := os.OpenFile(partial, flags, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, body); err != nil {
+		out.Close()
+		return fmt.Errorf("download of %s interrupted, run the command again to resume: %s", key, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	actual, err := fileChecksum(partial)
+	if err != nil {
+		return err
+	}
+
+	if actual != strings.TrimSpace(string(expected)) {
+		os.Remove(partial)
+		return fmt.Errorf("checksum mismatch for snapshot %s (expected %s, got %s)", key, strings.TrimSpace(string(expected)), actual)
+	}
+
+	return os.Rename(partial, file)
+}
+
+func readObject(backend SnapshotBackend, name string) ([]byte, error) {
+	body, _, err := backend.Open(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
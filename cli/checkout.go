@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+const MetaConfig = ".meta"
+
+// MissingDependency is a private dependency of a local project that is not checked out at the root
+type MissingDependency struct {
+	Folder     string
+	Version    string
+	RequiredBy []string
+}
+
+var gitHosts = map[string]string{
+	"github":    "github.com",
+	"gitlab":    "gitlab.com",
+	"bitbucket": "bitbucket.org",
+}
+
+var githubShorthand = regexp.MustCompile(`^[^/:@.][^/:@]*/[^/:@]+$`)
+
+var sshPath = regexp.MustCompile(`^ssh://([^/:]+:(?:[^0-9/]|[0-9]+[^0-9/]).*)$`)
+
+func Checkout() cli.Command {
+	return cli.Command{
+		Name:  "checkout",
+		Usage: "clones the private dependencies of local projects that are not checked out at the root",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.BoolFlag{Name: "dry-run", Usage: "print the private dependencies of checked out projects that would be cloned without cloning them"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce checkout requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			meta, err := loadMetaProjects(root)
+			if err != nil {
+				return err
+			}
+
+			selected, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			attempted := make(map[string]bool)
+			var failed []string
+			cloned := 0
+
+			// every clone can bring in more private dependencies, so keep going until none are missing
+			for {
+				found, err := missingPrivateDependencies(root, selected, exclude)
+				if err != nil {
+					return err
+				}
+
+				var missing []*MissingDependency
+				for _, m := range found {
+					if !attempted[m.Folder] {
+						missing = append(missing, m)
+					}
+				}
+
+				if len(missing) == 0 {
+					break
+				}
+
+				for _, m := range missing {
+					attempted[m.Folder] = true
+
+					url, ref, err := cloneURL(m.Version, meta[m.Folder])
+					if err != nil {
+						color.New(color.FgRed).Fprintf(c.App.Writer, "could not clone %s (required by %s): %s\n", m.Folder, strings.Join(m.RequiredBy, ", "), err)
+						failed = append(failed, m.Folder)
+						continue
+					}
+
+					if c.Bool("dry-run") {
+						fmt.Fprintf(c.App.Writer, "would clone %s from %s%s (required by %s)\n", m.Folder, url, atRef(ref), strings.Join(m.RequiredBy, ", "))
+						continue
+					}
+
+					if err := cloneProject(root, m.Folder, url, ref); err != nil {
+						color.New(color.FgRed).Fprintf(c.App.Writer, "could not clone %s (required by %s): %s\n", m.Folder, strings.Join(m.RequiredBy, ", "), err)
+						failed = append(failed, m.Folder)
+						continue
+					}
+
+					fmt.Fprintf(c.App.Writer, "cloned %s from %s%s (required by %s)\n", m.Folder, url, atRef(ref), strings.Join(m.RequiredBy, ", "))
+					selected = append(selected, m.Folder)
+					cloned++
+				}
+			}
+
+			if c.Bool("dry-run") {
+				// the manifests of projects that are not cloned cannot be read, so their own private
+				// dependencies are only found by a real checkout
+				if len(attempted) > len(failed) {
+					color.New(color.FgYellow).Fprintf(c.App.Writer, "\nthe private dependencies of projects that would be cloned are not listed until they are cloned\n")
+				}
+
+				return nil
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\ncloned %d private project(s)\n", cloned)
+
+			if len(failed) > 0 {
+				return fmt.Errorf("could not clone %d private project(s): %s", len(failed), strings.Join(failed, ", "))
+			}
+
+			return nil
+		},
+	}
+}
+
+// loadMetaProjects reads the folder to git URL map of the projects in a .meta file, returning an empty map
+// if there is no .meta file at the root
+func loadMetaProjects(root string) (map[string]string, error) {
+	meta := struct {
+		Projects map[string]string `json:"projects"`
+	}{Projects: make(map[string]string)}
+
+	bytes, err := ioutil.ReadFile(filepath.Join(root, MetaConfig))
+	if os.IsNotExist(err) {
+		return meta.Projects, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(bytes, &meta); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", MetaConfig, err)
+	}
+
+	return meta.Projects, nil
+}
+
+// missingPrivateDependencies finds the private dependencies of the selected projects that do not match any
+// project checked out at the root, keyed by the folder they would be cloned into
+func missingPrivateDependencies(root string, selected []string, exclude []string) ([]*MissingDependency, error) {
+	all, err := getProjectFolders(root, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	allProjects, err := loadProjects(root, all)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := loadProjects(root, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newProjectGraph(allProjects, exclude)
+	packageNames := make(map[string]string)
+	for _, p := range allProjects {
+		packageNames[p.PackageName()] = p.Name
+	}
+
+	missing := make(map[string]*MissingDependency)
+	for _, p := range projects {
+		for _, field := range []string{"dependencies", "devDependencies"} {
+			for dep, version := range p.Dependencies(field) {
+				if !isAPrivateDependency(version, exclude...) {
+					continue
+				}
+
+				if _, ok := g.resolve(dep, version, packageNames, exclude); ok {
+					continue
+				}
+
+				folder := repositoryName(version)
+				if _, ok := missing[folder]; !ok {
+					missing[folder] = &MissingDependency{Folder: folder, Version: version}
+				}
+
+				if !contains(missing[folder].RequiredBy, p.Name) {
+					missing[folder].RequiredBy = append(missing[folder].RequiredBy, p.Name)
+				}
+			}
+		}
+	}
+
+	var sorted []*MissingDependency
+	for _, m := range missing {
+		sort.Strings(m.RequiredBy)
+		sorted = append(sorted, m)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Folder < sorted[j].Folder
+	})
+
+	return sorted, nil
+}
+
+// cloneURL derives the URL to clone a private dependency from and the ref to check out from its version,
+// using the URL in the .meta file instead if there is one
+func cloneURL(version, metaURL string) (string, string, error) {
+	spec, ref := version, ""
+	if i := strings.Index(version, "#"); i > -1 {
+		spec, ref = version[:i], version[i+1:]
+	}
+
+	if metaURL != "" {
+		return metaURL, ref, nil
+	}
+
+	spec = strings.TrimPrefix(spec, "git+")
+	if i := strings.Index(spec, ":"); i > -1 {
+		if host, ok := gitHosts[spec[:i]]; ok {
+			return fmt.Sprintf("git@%s:%s.git", host, strings.TrimSuffix(spec[i+1:], ".git")), ref, nil
+		}
+	}
+
+	if githubShorthand.MatchString(spec) {
+		return fmt.Sprintf("git@github.com:%s.git", strings.TrimSuffix(spec, ".git")), ref, nil
+	}
+
+	// npm accepts scp-style paths after ssh://, which git would read as a port, so they are cloned scp-style
+	if m := sshPath.FindStringSubmatch(spec); m != nil {
+		return m[1], ref, nil
+	}
+
+	if strings.Contains(spec, "://") || strings.HasPrefix(spec, "git@") {
+		return spec, ref, nil
+	}
+
+	return "", "", fmt.Errorf("could not derive a git URL from version \"%s\"", version)
+}
+
+// cloneProject clones a private dependency into a folder at the root and checks out the given ref, which
+// npm allows to be a branch, tag or commit
+func cloneProject(root, folder, url, ref string) error {
+	if _, err := git(root, "clone", "--quiet", url, folder); err != nil {
+		return err
+	}
+
+	if ref == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(ref, "semver:") {
+		return fmt.Errorf("cloned %s but cannot check out a semver range (%s)", folder, ref)
+	}
+
+	_, err := git(filepath.Join(root, folder), "checkout", "--quiet", ref)
+	return err
+}
+
+func atRef(ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	return "#" + ref
+}
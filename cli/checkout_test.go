@@ -0,0 +1,156 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func gitRun(dir string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=triforce", "-c", "user.email=triforce@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
+
+	return nil
+}
+
+var _ = Describe("Checkout", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+	var remotes string
+
+	// writeRemote creates a git repository outside of the projects at the root to clone from
+	writeRemote := func(name string, pkg *BasicPackageJSON) string {
+		dir := filepath.Join(remotes, name)
+		Expect(os.MkdirAll(dir, os.FileMode(0700))).To(Succeed())
+
+		bytes, err := json.MarshalIndent(pkg, "", "  ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "package.json"), bytes, os.FileMode(0666))).To(Succeed())
+		Expect(gitCommitAll(dir)).To(Succeed())
+
+		return "git+file://" + dir
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should clone missing private dependencies until every private dependency is checked out", func() {
+		p["api-1"] = NewBasicPackageJSONBuilder().Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		remotes, err = filepath.Abs(filepath.Join(t.RootFolder, ".remotes"))
+		Expect(err).NotTo(HaveOccurred())
+
+		lib2 := writeRemote("lib-2", NewBasicPackageJSONBuilder().Build())
+		lib1 := writeRemote("lib-1", NewBasicPackageJSONBuilder().Dependency("lib-2", lib2).Build())
+
+		lib1Dir := filepath.Join(remotes, "lib-1")
+		Expect(gitRun(lib1Dir, "tag", "v1.0.0")).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(lib1Dir, "CHANGELOG.md"), []byte("unreleased\n"), os.FileMode(0666))).To(Succeed())
+		Expect(gitRun(lib1Dir, "add", "--all")).To(Succeed())
+		Expect(gitRun(lib1Dir, "commit", "--quiet", "--message", "unreleased change")).To(Succeed())
+
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", lib1+"#v1.0.0").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("only listing the private dependencies of checked out projects in a dry run", func() {
+			args := []string{"triforce", "checkout", "--dry-run", "--exclude", "remotes", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("would clone lib-1"))
+			Expect(out.String()).NotTo(ContainSubstring("would clone lib-2"))
+			Expect(out.String()).To(ContainSubstring("are not listed until they are cloned"))
+			out.Reset()
+		})
+
+		args := []string{"triforce", "checkout", "--exclude", "remotes", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		_, err = os.Stat(filepath.Join(t.RootFolder, "lib-1", "package.json"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = os.Stat(filepath.Join(t.RootFolder, "lib-2", "package.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking out the ref in the version", func() {
+			_, err := os.Stat(filepath.Join(t.RootFolder, "lib-1", "CHANGELOG.md"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		Expect(out.String()).To(ContainSubstring("cloned lib-1 from " + lib1[len("git+"):] + "#v1.0.0 (required by api-1)"))
+		Expect(out.String()).To(ContainSubstring("cloned lib-2 from " + lib2[len("git+"):] + " (required by lib-1)"))
+		Expect(out.String()).To(ContainSubstring("cloned 2 private project(s)"))
+	})
+
+	It("should prefer the git URL of a project in the .meta file", func() {
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		remotes, err = filepath.Abs(filepath.Join(t.RootFolder, ".remotes"))
+		Expect(err).NotTo(HaveOccurred())
+
+		lib1 := writeRemote("lib-1", NewBasicPackageJSONBuilder().Build())
+		meta := fmt.Sprintf(`{"projects": {"api-1": "git@github.com:someorg/api-1.git", "lib-1": "%s"}}`, lib1[len("git+"):])
+		Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, ".meta"), []byte(meta), os.FileMode(0666))).To(Succeed())
+
+		args := []string{"triforce", "checkout", "--dry-run", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("would clone lib-1 from " + lib1[len("git+"):]))
+
+		args = []string{"triforce", "checkout", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		_, err = os.Stat(filepath.Join(t.RootFolder, "lib-1", "package.json"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should clone ssh URLs with scp-style paths the way git expects them", func() {
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "git+ssh://git@github.com:acme/lib-1.git#develop").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		args := []string{"triforce", "checkout", "--dry-run", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("would clone lib-1 from git@github.com:acme/lib-1.git#develop (required by api-1)"))
+	})
+
+	It("should throw an error if a private dependency cannot be cloned", func() {
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		args := []string{"triforce", "checkout", "--dry-run", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("would clone lib-1 from git@github.com:someorg/lib-1.git (required by api-1)"))
+
+		Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, ".meta"), []byte(`{"projects": {"lib-1": "file:///does/not/exist"}}`), os.FileMode(0666))).To(Succeed())
+
+		args = []string{"triforce", "checkout", t.RootFolder}
+		Expect(app.Run(args)).NotTo(Succeed())
+		Expect(out.String()).To(ContainSubstring("could not clone lib-1 (required by api-1)"))
+	})
+})
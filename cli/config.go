@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const TriforceConfig = ".triforce.json"
+
+type Config struct {
+	Groups  map[string]*Group `json:"groups"`
+	Install InstallConfig     `json:"install"`
+}
+
+// InstallConfig sets the package manager that triforce install runs and the arguments it is run with
+type InstallConfig struct {
+	Client string   `json:"client"`
+	Args   []string `json:"args"`
+}
+
+// Group selects projects by folder name glob patterns or by the tags listed under "triforce.tags" in
+// their package.json files, and is assembled and linked in its own directory relative to the root
+type Group struct {
+	Patterns  []string `json:"patterns"`
+	Tags      []string `json:"tags"`
+	Directory string   `json:"directory"`
+}
+
+func loadConfig(root string) (*Config, error) {
+	config := &Config{Groups: make(map[string]*Group)}
+
+	bytes, err := ioutil.ReadFile(filepath.Join(root, TriforceConfig))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(bytes, config); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", TriforceConfig, err)
+	}
+
+	for name, group := range config.Groups {
+		if group.Directory == "" {
+			group.Directory = name
+		}
+	}
+
+	return config, nil
+}
+
+func (c *Config) GroupNames() []string {
+	var names []string
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (g *Group) Matches(p *Project) bool {
+	for _, pattern := range g.Patterns {
+		if matched, _ := filepath.Match(pattern, p.Name); matched {
+			return true
+		}
+	}
+
+	for _, tag := range g.Tags {
+		for _, projectTag := range p.Tags() {
+			if tag == projectTag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (g *Group) Select(projects []*Project) []*Project {
+	var selected []*Project
+	for _, p := range projects {
+		if g.Matches(p) {
+			selected = append(selected, p)
+		}
+	}
+
+	return selected
+}
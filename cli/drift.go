@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+type DriftReport struct {
+	Generated    time.Time          `json:"generated"`
+	Dependencies []*DependencyDrift `json:"dependencies"`
+}
+
+// DependencyDrift describes a dependency declared with more than one version across projects, scored
+// by the number of distinct major and major.minor versions
+type DependencyDrift struct {
+	Dependency string          `json:"dependency"`
+	Majors     int             `json:"majors"`
+	Minors     int             `json:"minors"`
+	Versions   []*DriftVersion `json:"versions"`
+}
+
+type DriftVersion struct {
+	Version  string   `json:"version"`
+	Projects []string `json:"projects"`
+}
+
+func Drift() cli.Command {
+	return cli.Command{
+		Name:  "drift",
+		Usage: "reports dependencies declared with different versions across projects",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "format", Usage: "output format (table, json or html)", Value: "table"},
+			cli.StringFlag{Name: "output, o", Usage: "file to write the report to instead of stdout"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce drift requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			projectFolders, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, projectFolders)
+			if err != nil {
+				return err
+			}
+
+			report := &DriftReport{
+				Generated:    time.Now().UTC(),
+				Dependencies: findDrift(collectDeclarations(projects, exclude)),
+			}
+
+			var exported []byte
+			switch c.String("format") {
+			case "table":
+				exported = report.Table()
+			case "json":
+				if exported, err = json.MarshalIndent(report, "", "  "); err != nil {
+					return err
+				}
+			case "html":
+				if exported, err = report.HTML(); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown drift format %s", c.String("format"))
+			}
+
+			if output := c.String("output"); output != "" {
+				return ioutil.WriteFile(output, exported, os.FileMode(0666))
+			}
+
+			_, err = c.App.Writer.Write(exported)
+			return err
+		},
+	}
+}
+
+// findDrift returns the dependencies declared with more than one version, with the most drifted first
+func findDrift(declarations map[string][]Declaration) []*DependencyDrift {
+	drifts := []*DependencyDrift{}
+
+	for _, dep := range sortedDependencyNames(declarations) {
+		byVersion := make(map[string][]string)
+		majors := make(map[int]bool)
+		minors := make(map[string]bool)
+
+		for _, d := range declarations[dep] {
+			if !contains(byVersion[d.Version], d.Project) {
+				byVersion[d.Version] = append(byVersion[d.Version], d.Project)
+			}
+
+			if v, ok := rangeVersion(d.Version); ok {
+				majors[v.Major] = true
+				minors[fmt.Sprintf("%d.%d", v.Major, v.Minor)] = true
+			}
+		}
+
+		if len(byVersion) < 2 {
+			continue
+		}
+
+		drift := &DependencyDrift{Dependency: dep, Majors: len(majors), Minors: len(minors)}
+		for version, projects := range byVersion {
+			drift.Versions = append(drift.Versions, &DriftVersion{Version: version, Projects: projects})
+		}
+
+		sort.Slice(drift.Versions, func(i, j int) bool {
+			return drift.Versions[i].Version < drift.Versions[j].Version
+		})
+
+		drifts = append(drifts, drift)
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Majors != drifts[j].Majors {
+			return drifts[i].Majors > drifts[j].Majors
+		}
+
+		return drifts[i].Minors > drifts[j].Minors
+	})
+
+	return drifts
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *DriftReport) Table() []byte {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "DEPENDENCY\tMAJORS\tMINORS\tVERSION\tPROJECTS")
+	for _, drift := range r.Dependencies {
+		for i, version := range drift.Versions {
+			if i == 0 {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", drift.Dependency, drift.Majors, drift.Minors, version.Version, strings.Join(version.Projects, ", "))
+				continue
+			}
+
+			fmt.Fprintf(tw, "\t\t\t%s\t%s\n", version.Version, strings.Join(version.Projects, ", "))
+		}
+	}
+
+	tw.Flush()
+	return b.Bytes()
+}
+
+var driftTemplate = template.Must(template.New("drift").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>triforce dependency drift</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; }
+    table { border-collapse: collapse; }
+    th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+    th { background: #eee; }
+  </style>
+</head>
+<body>
+  <h1>Dependency drift</h1>
+  <p>Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}, {{len .Dependencies}} drifted dependencies</p>
+  <table>
+    <tr><th>Dependency</th><th>Majors</th><th>Minors</th><th>Version</th><th>Projects</th></tr>
+    {{- range .Dependencies}}{{$drift := .}}{{range $i, $version := .Versions}}
+    <tr>{{if eq $i 0}}<td rowspan="{{len $drift.Versions}}">{{$drift.Dependency}}</td><td rowspan="{{len $drift.Versions}}">{{$drift.Majors}}</td><td rowspan="{{len $drift.Versions}}">{{$drift.Minors}}</td>{{end}}<td>{{$version.Version}}</td><td>{{join $version.Projects ", "}}</td></tr>
+    {{- end}}{{end}}
+  </table>
+</body>
+</html>
+`))
+
+func (r *DriftReport) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := driftTemplate.Execute(&b, r); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type DriftReport struct {
+	Dependencies []struct {
+		Dependency string `json:"dependency"`
+		Majors     int    `json:"majors"`
+		Minors     int    `json:"minors"`
+		Versions   []struct {
+			Version  string   `json:"version"`
+			Projects []string `json:"projects"`
+		} `json:"versions"`
+	} `json:"dependencies"`
+}
+
+var _ = Describe("Drift", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["api-1"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^3.10.0").
+			Dependency("dep-b", "~1.1.0").
+			Dependency("dep-c", "1.0.0").
+			Build()
+
+		p["api-2"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^4.17.0").
+			DevDependency("dep-b", "~1.2.0").
+			Dependency("dep-c", "1.0.0").
+			Build()
+
+		p["app-1"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^4.17.0").
+			Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should throw an error if given an unknown format", func() {
+		args := []string{"triforce", "drift", "--format", "csv", t.RootFolder}
+		Expect(app.Run(args)).NotTo(Succeed())
+	})
+
+	It("should list every dependency declared with more than one version, most drifted first", func() {
+		output := filepath.Join(t.RootFolder, "drift.json")
+		args := []string{"triforce", "drift", "--format", "json", "--output", output, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		bytes, err := ioutil.ReadFile(output)
+		Expect(err).NotTo(HaveOccurred())
+		report := DriftReport{}
+		Expect(json.Unmarshal(bytes, &report)).To(Succeed())
+
+		Expect(report.Dependencies).To(HaveLen(2))
+		Expect(report.Dependencies[0].Dependency).To(Equal("dep-a"))
+		Expect(report.Dependencies[0].Majors).To(Equal(2))
+		Expect(report.Dependencies[0].Minors).To(Equal(2))
+		Expect(report.Dependencies[0].Versions[1].Version).To(Equal("^4.17.0"))
+		Expect(report.Dependencies[0].Versions[1].Projects).To(Equal([]string{"api-2", "app-1"}))
+
+		Expect(report.Dependencies[1].Dependency).To(Equal("dep-b"))
+		Expect(report.Dependencies[1].Majors).To(Equal(1))
+		Expect(report.Dependencies[1].Minors).To(Equal(2))
+	})
+
+	It("should print the drift as a table", func() {
+		args := []string{"triforce", "drift", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).To(MatchRegexp(`DEPENDENCY\s+MAJORS\s+MINORS\s+VERSION\s+PROJECTS`))
+		Expect(out.String()).To(MatchRegexp(`dep-a\s+2\s+2\s+\^3\.10\.0\s+api-1\n\s+\^4\.17\.0\s+api-2, app-1\n`))
+		Expect(out.String()).NotTo(ContainSubstring("dep-c"))
+	})
+
+	It("should render the drift as a static html page", func() {
+		args := []string{"triforce", "drift", "--format", "html", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).To(HavePrefix("<!DOCTYPE html>"))
+		Expect(out.String()).To(ContainSubstring(`<td rowspan="2">dep-a</td>`))
+		Expect(out.String()).To(ContainSubstring(`<td>api-2, app-1</td>`))
+	})
+})
@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+var prefixColors = []color.Attribute{color.FgCyan, color.FgMagenta, color.FgBlue, color.FgYellow, color.FgGreen}
+
+func Exec() cli.Command {
+	return cli.Command{
+		Name:      "exec",
+		ShortName: "x",
+		Usage:     "runs a command in every project in dependency order",
+		UsageText: "triforce exec [command options] root -- command [arguments...]",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "since, s", Usage: "only include projects affected by changes since this git ref"},
+			cli.IntFlag{Name: "concurrency, c", Usage: "maximum number of projects to run the command in at the same time", Value: 1},
+			cli.BoolFlag{Name: "keep-going, k", Usage: "keep running the command in projects that do not depend on a failed project"},
+		},
+		Action: func(c *cli.Context) error {
+			args := c.Args()
+			if len(args) > 1 && args[1] == "--" {
+				args = append(cli.Args{args[0]}, args[2:]...)
+			}
+
+			if len(args) < 2 {
+				return fmt.Errorf("triforce exec requires a root meta or monorepo folder and a command as arguments")
+			}
+
+			root, err := filepath.Abs(args.First())
+			if err != nil {
+				return err
+			}
+
+			g, err := loadProjectGraph(root, c.StringSlice("filter"), c.String("since"), c.StringSlice("exclude"), c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			commands := make(map[string][]string)
+			for _, name := range g.Names() {
+				commands[name] = args.Tail()
+			}
+
+			return runAndSummarise(c, g, commands)
+		},
+	}
+}
+
+func runAndSummarise(c *cli.Context, g *ProjectGraph, commands map[string][]string) error {
+	levels, cycles := g.Order()
+	for _, cycle := range cycles {
+		color.New(color.FgYellow).Fprintf(c.App.Writer, "cycle detected between local projects: %s\n", strings.Join(cycle, " -> "))
+	}
+
+	runner := &Runner{
+		Writer:      c.App.Writer,
+		Graph:       g,
+		Concurrency: c.Int("concurrency"),
+		KeepGoing:   c.Bool("keep-going"),
+	}
+
+	results := runner.Run(levels, commands)
+	writeSummary(c.App.Writer, results)
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == statusFailed {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d project(s)", failed)
+	}
+
+	return nil
+}
+
+type Result struct {
+	Project  string
+	Status   string
+	ExitCode int
+	Duration time.Duration
+}
+
+// Runner runs commands in projects level by level, so that a project only starts once every local
+// project it depends on has finished
+type Runner struct {
+	Writer      io.Writer
+	Graph       *ProjectGraph
+	Concurrency int
+	KeepGoing   bool
+
+	mutex sync.Mutex
+}
+
+// Run runs the command for each project in the given levels, skipping projects without a command and
+// projects that depend on a failed project, and stopping after the first failing level unless the
+// runner keeps going
+func (r *Runner) Run(levels [][]string, commands map[string][]string) []*Result {
+	var results []*Result
+	broken := make(map[string]bool)
+	stopped := false
+
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	colors := make(map[string]color.Attribute)
+	for i, name := range r.Graph.Names() {
+		colors[name] = prefixColors[i%len(prefixColors)]
+	}
+
+	for _, level := range levels {
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, concurrency)
+		levelResults := make([]*Result, len(level))
+
+		for i, name := range level {
+			command, ok := commands[name]
+			if !ok || stopped || r.dependsOnBroken(name, broken) {
+				levelResults[i] = &Result{Project: name, Status: statusSkipped, ExitCode: -1}
+				continue
+			}
+
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(i int, name string, command []string) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				levelResults[i] = r.run(name, command, colors[name])
+			}(i, name, command)
+		}
+
+		wg.Wait()
+
+		for _, result := range levelResults {
+			if result.Status == statusFailed || (result.Status == statusSkipped && r.dependsOnBroken(result.Project, broken)) {
+				broken[result.Project] = true
+			}
+
+			if result.Status == statusFailed && !r.KeepGoing {
+				stopped = true
+			}
+		}
+
+		results = append(results, levelResults...)
+	}
+
+	return results
+}
+
+func (r *Runner) dependsOnBroken(name string, broken map[string]bool) bool {
+	for _, dep := range r.Graph.Dependencies[name] {
+		if broken[dep] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Runner) run(name string, command []string, attribute color.Attribute) *Result {
+	prefix := color.New(attribute).Sprintf("[%s]", name) + " "
+	stdout := &prefixWriter{mutex: &r.mutex, writer: r.Writer, prefix: prefix}
+	stderr := &prefixWriter{mutex: &r.mutex, writer: r.Writer, prefix: prefix}
+
+	// a single argument is treated as a shell command line so that pipes and redirects work
+	cmd := exec.Command(command[0], command[1:]...)
+	if len(command) == 1 {
+		cmd = exec.Command("sh", "-c", command[0])
+	}
+
+	cmd.Dir = r.Graph.Projects[name].Path
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	result := &Result{Project: name, Status: statusOK, Duration: time.Since(start)}
+
+	stdout.Flush()
+	stderr.Flush()
+
+	if err != nil {
+		result.Status = statusFailed
+		result.ExitCode = -1
+
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				result.ExitCode = status.ExitStatus()
+			}
+		} else {
+			stderr.Write([]byte(err.Error() + "\n"))
+			stderr.Flush()
+		}
+	}
+
+	return result
+}
+
+func writeSummary(w io.Writer, results []*Result) {
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tSTATUS\tEXIT CODE\tDURATION")
+	for _, result := range results {
+		exitCode, duration := "-", "-"
+		if result.Status != statusSkipped {
+			exitCode = fmt.Sprintf("%d", result.ExitCode)
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Project, result.Status, exitCode, duration)
+	}
+
+	tw.Flush()
+}
+
+// prefixWriter writes complete lines to the underlying writer with a prefix, holding on to any partial
+// line until it is completed or flushed
+type prefixWriter struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+	prefix string
+	buffer []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buffer = append(p.buffer, b...)
+
+	for {
+		i := bytes.IndexByte(p.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		p.writeLine(p.buffer[:i+1])
+		p.buffer = p.buffer[i+1:]
+	}
+
+	return len(b), nil
+}
+
+func (p *prefixWriter) Flush() {
+	if len(p.buffer) > 0 {
+		p.writeLine(append(p.buffer, '\n'))
+		p.buffer = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fmt.Fprintf(p.writer, "%s%s", p.prefix, line)
+}
@@ -0,0 +1,87 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exec", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	BeforeEach(func() {
+		p = make(map[string]*BasicPackageJSON)
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	Context("sanity checking", func() {
+		It("should throw an error if trying to exec without a command", func() {
+			args := []string{"triforce", "exec", "."}
+			Expect(app.Run(args)).NotTo(Succeed())
+		})
+	})
+
+	Context("projects depending on other local projects", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+			p["app-1"] = NewBasicPackageJSONBuilder().Build()
+			p["lib-1"] = NewBasicPackageJSONBuilder().Dependency("lib-2", "github:someorg/lib-2").Build()
+			p["lib-2"] = NewBasicPackageJSONBuilder().Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should run the command in every project in dependency order", func() {
+			args := []string{"triforce", "exec", t.RootFolder, "--", "sh", "-c", "basename $PWD >> ../order.txt"}
+			Expect(app.Run(args)).To(Succeed())
+
+			order, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "order.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(order)).To(Equal("app-1\nlib-2\nlib-1\napi-1\n"))
+		})
+
+		It("should prefix the output of each project and print a summary", func() {
+			args := []string{"triforce", "exec", "--concurrency", "4", t.RootFolder, "--", "echo hello from $(basename $PWD)"}
+			Expect(app.Run(args)).To(Succeed())
+
+			Expect(out.String()).To(ContainSubstring("[lib-2] hello from lib-2\n"))
+			Expect(out.String()).To(ContainSubstring("[api-1] hello from api-1\n"))
+			Expect(out.String()).To(MatchRegexp(`PROJECT\s+STATUS\s+EXIT CODE\s+DURATION`))
+			Expect(out.String()).To(MatchRegexp(`api-1\s+ok\s+0\s+`))
+		})
+
+		It("should stop after the first failure when failing fast", func() {
+			args := []string{"triforce", "exec", t.RootFolder, "--", "test $(basename $PWD) != app-1"}
+			Expect(app.Run(args)).NotTo(Succeed())
+
+			Expect(out.String()).To(MatchRegexp(`app-1\s+failed\s+1\s+`))
+			Expect(out.String()).To(MatchRegexp(`lib-1\s+skipped\s+-\s+-`))
+			Expect(out.String()).To(MatchRegexp(`api-1\s+skipped\s+-\s+-`))
+		})
+
+		It("should keep going in projects that do not depend on a failed project", func() {
+			args := []string{"triforce", "exec", "--keep-going", t.RootFolder, "--", "test $(basename $PWD) != lib-2"}
+			Expect(app.Run(args)).NotTo(Succeed())
+
+			Expect(out.String()).To(MatchRegexp(`app-1\s+ok\s+0\s+`))
+			Expect(out.String()).To(MatchRegexp(`lib-2\s+failed\s+1\s+`))
+			Expect(out.String()).To(MatchRegexp(`lib-1\s+skipped\s+-\s+-`))
+			Expect(out.String()).To(MatchRegexp(`api-1\s+skipped\s+-\s+-`))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Jeffail/gabs"
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func Freeze() cli.Command {
+	return cli.Command{
+		Name:  "freeze",
+		Usage: "pins the assembled package.json file to the exact versions installed in node_modules",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "manifest, m", Usage: "assembled package.json file that was installed (defaults to the package.json file at the root)"},
+			cli.StringFlag{Name: "output, o", Usage: "file to write the frozen package.json file to instead of rewriting the assembled package.json file"},
+			cli.BoolFlag{Name: "projects", Usage: "also pin the dependencies in the package.json file of each project to the versions they resolve"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce freeze requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			manifestPath := c.String("manifest")
+			if manifestPath == "" {
+				manifestPath = filepath.Join(root, PackageJSON)
+			}
+
+			output := c.String("output")
+			if output == "" {
+				output = manifestPath
+			}
+
+			manifest, err := gabs.ParseJSONFile(manifestPath)
+			if err != nil {
+				return err
+			}
+
+			nodeModules := filepath.Join(root, NodeModules)
+			if _, err := os.Stat(nodeModules); err != nil {
+				return fmt.Errorf("no node_modules folder found at %s", root)
+			}
+
+			installed, err := readInstalledPackages(nodeModules)
+			if err != nil {
+				return err
+			}
+
+			pins := make(map[string]string)
+			for _, field := range []string{"dependencies", "devDependencies"} {
+				if data, ok := manifest.Path(field).Data().(map[string]interface{}); ok {
+					var deps []string
+					for dep := range data {
+						deps = append(deps, dep)
+					}
+					sort.Strings(deps)
+
+					for _, dep := range deps {
+						if pkg, ok := installed[dep]; ok && !pkg.Linked && pkg.Version != "" {
+							pins[dep] = pkg.Version
+						} else {
+							color.New(color.FgYellow).Fprintf(c.App.Writer, "could not pin \"%s\" (not installed in %s)\n", dep, nodeModules)
+						}
+					}
+				}
+			}
+
+			changes, err := rewriteFile(manifestPath, output, pins, exclude, false)
+			if err != nil {
+				return err
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\n%s\n", output)
+			for _, change := range changes {
+				fmt.Fprintln(c.App.Writer, change.Describe("pinned"))
+			}
+
+			if !c.Bool("projects") {
+				return nil
+			}
+
+			projectFolders, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, projectFolders)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range projects {
+				if _, err := rewriteProject(c, p, resolvedPins(root, p), exclude, "pinned", false); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// resolvedPins returns the installed version that node would resolve for each dependency of a project,
+// ignoring linked projects
+func resolvedPins(root string, p *Project) map[string]string {
+	pins := make(map[string]string)
+
+	for _, field := range []string{"dependencies", "devDependencies"} {
+		for dep := range p.Dependencies(field) {
+			dir, ok := resolvePackage(root, p.Path, dep)
+			if !ok {
+				continue
+			}
+
+			if info, err := os.Lstat(dir); err != nil || info.Mode()&os.ModeSymlink != 0 {
+				continue
+			}
+
+			if parsed, err := gabs.ParseJSONFile(filepath.Join(dir, PackageJSON)); err == nil {
+				if version, ok := parsed.Path("version").Data().(string); ok && version != "" {
+					pins[dep] = version
+				}
+			}
+		}
+	}
+
+	return pins
+}
@@ -0,0 +1,91 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Freeze", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	readPackageJSON := func(file string) *BasicPackageJSON {
+		bytes, err := ioutil.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		pkg := &BasicPackageJSON{}
+		Expect(json.Unmarshal(bytes, pkg)).To(Succeed())
+		return pkg
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["api-1"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.0.0").
+			DevDependency("dep-b", "~2.1.0").
+			Dependency("lib-1", "github:someorg/lib-1").
+			Build()
+
+		p["api-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^0.9.0").Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.0.0").
+			DevDependency("dep-b", "~2.1.0").
+			Build())).To(Succeed())
+
+		Expect(t.Install("dep-a", "1.4.2")).To(Succeed())
+		Expect(t.Install("dep-b", "2.1.7")).To(Succeed())
+		Expect(t.InstallInProject("api-2", "dep-a", "0.9.3")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should pin the assembled package.json file to the installed versions", func() {
+		args := []string{"triforce", "freeze", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		pkg := readPackageJSON(filepath.Join(t.RootFolder, "package.json"))
+		Expect(pkg.Dependencies).To(Equal(map[string]string{"dep-a": "1.4.2"}))
+		Expect(pkg.DevDependencies).To(Equal(map[string]string{"dep-b": "2.1.7"}))
+	})
+
+	It("should write the pinned versions to a separate copy", func() {
+		output := filepath.Join(t.RootFolder, "package.frozen.json")
+		args := []string{"triforce", "freeze", "--output", output, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(readPackageJSON(output).Dependencies).To(Equal(map[string]string{"dep-a": "1.4.2"}))
+		Expect(readPackageJSON(filepath.Join(t.RootFolder, "package.json")).Dependencies).To(Equal(map[string]string{"dep-a": "^1.0.0"}))
+	})
+
+	It("should pin each project to the versions it resolves", func() {
+		args := []string{"triforce", "freeze", "--projects", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		api1 := readPackageJSON(filepath.Join(t.RootFolder, "api-1", "package.json"))
+		Expect(api1.Dependencies).To(Equal(map[string]string{"dep-a": "1.4.2", "lib-1": "github:someorg/lib-1"}))
+		Expect(api1.DevDependencies).To(Equal(map[string]string{"dep-b": "2.1.7"}))
+
+		api2 := readPackageJSON(filepath.Join(t.RootFolder, "api-2", "package.json"))
+		Expect(api2.Dependencies).To(Equal(map[string]string{"dep-a": "0.9.3"}))
+
+		Expect(out.String()).To(ContainSubstring(`pinned dependency "dep-a" from version "^0.9.0" to "0.9.3"`))
+	})
+})
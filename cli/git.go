@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed in %s: %s", strings.Join(args, " "), dir, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func isGitCheckout(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// changedFiles lists files relative to dir that differ from the given ref, including untracked files
+func changedFiles(dir, ref string) ([]string, error) {
+	diff, err := git(dir, "diff", "--name-only", "--relative", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(diff+"\n"+untracked, "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
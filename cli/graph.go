@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/urfave/cli"
+)
+
+// ProjectGraph models which local projects depend on which, keyed by project folder name
+type ProjectGraph struct {
+	Projects     map[string]*Project
+	Dependencies map[string][]string
+}
+
+func Graph() cli.Command {
+	return cli.Command{
+		Name:      "graph",
+		ShortName: "g",
+		Usage:     "exports the dependency graph of local projects as dot, mermaid or json",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "format", Usage: "output format (dot, mermaid or json)", Value: "dot"},
+			cli.StringFlag{Name: "output, o", Usage: "file to write the graph to instead of stdout"},
+			cli.BoolFlag{Name: "public", Usage: "include public dependencies in the graph"},
+			cli.IntFlag{Name: "public-limit", Usage: "maximum number of public dependencies to include per project (0 for no limit)", Value: 10},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce graph requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			g, err := loadProjectGraph(root, c.StringSlice("filter"), "", exclude, c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			public := make(map[string][]string)
+			if c.Bool("public") {
+				for _, name := range g.Names() {
+					public[name] = g.PublicDependencies(name, exclude)
+					if limit := c.Int("public-limit"); limit > 0 && len(public[name]) > limit {
+						public[name] = public[name][:limit]
+					}
+				}
+			}
+
+			var exported []byte
+			switch c.String("format") {
+			case "dot":
+				exported = g.Dot(public)
+			case "mermaid":
+				exported = g.Mermaid(public)
+			case "json":
+				if exported, err = g.JSON(public); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown graph format %s", c.String("format"))
+			}
+
+			if output := c.String("output"); output != "" {
+				return ioutil.WriteFile(output, exported, os.FileMode(0666))
+			}
+
+			_, err = c.App.Writer.Write(exported)
+			return err
+		},
+	}
+}
+
+func loadProjectGraph(root string, filter []string, since string, exclude []string, warnings io.Writer) (*ProjectGraph, error) {
+	projectDirectories, err := selectProjectFolders(root, filter, since, exclude, warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := loadProjects(root, projectDirectories)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProjectGraph(projects, exclude), nil
+}
+
+func newProjectGraph(projects []*Project, exclude []string) *ProjectGraph {
+	g := &ProjectGraph{
+		Projects:     make(map[string]*Project),
+		Dependencies: make(map[string][]string),
+	}
+
+	packageNames := make(map[string]string)
+	for _, p := range projects {
+		g.Projects[p.Name] = p
+		packageNames[p.PackageName()] = p.Name
+	}
+
+	for _, p := range projects {
+		seen := make(map[string]bool)
+		for _, field := range []string{"dependencies", "devDependencies"} {
+			for dep, version := range p.Dependencies(field) {
+				local, ok := g.resolve(dep, version, packageNames, exclude)
+				if !ok || local == p.Name || seen[local] {
+					continue
+				}
+
+				seen[local] = true
+				g.Dependencies[p.Name] = append(g.Dependencies[p.Name], local)
+			}
+		}
+
+		sort.Strings(g.Dependencies[p.Name])
+	}
+
+	return g
+}
+
+// resolve matches a dependency against the local projects, first by package name and folder
+// name, and then by the repository name of a private dependency version
+func (g *ProjectGraph) resolve(dep, version string, packageNames map[string]string, exclude []string) (string, bool) {
+	if local, ok := packageNames[dep]; ok {
+		return local, true
+	}
+
+	if _, ok := g.Projects[dep]; ok {
+		return dep, true
+	}
+
+	if isAPrivateDependency(version, exclude...) {
+		if _, ok := g.Projects[repositoryName(version)]; ok {
+			return repositoryName(version), true
+		}
+	}
+
+	return "", false
+}
+
+func (g *ProjectGraph) Names() []string {
+	var names []string
+	for name := range g.Projects {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (g *ProjectGraph) Dependents(name string) []string {
+	var dependents []string
+	for _, project := range g.Names() {
+		for _, dep := range g.Dependencies[project] {
+			if dep == name {
+				dependents = append(dependents, project)
+			}
+		}
+	}
+
+	return dependents
+}
+
+// Closure returns the named projects and every local project reachable from them
+func (g *ProjectGraph) Closure(names ...string) ([]*Project, error) {
+	visited := make(map[string]bool)
+	queue := append([]string{}, names...)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		if visited[name] {
+			continue
+		}
+
+		if _, ok := g.Projects[name]; !ok {
+			return nil, fmt.Errorf("no project named %s found", name)
+		}
+
+		visited[name] = true
+		queue = append(queue, g.Dependencies[name]...)
+	}
+
+	var projects []*Project
+	for _, name := range g.Names() {
+		if visited[name] {
+			projects = append(projects, g.Projects[name])
+		}
+	}
+
+	return projects, nil
+}
+
+// PublicDependencies returns the sorted public dependencies of a project, excluding local projects and
+// private dependencies
+func (g *ProjectGraph) PublicDependencies(name string, exclude []string) []string {
+	local := make(map[string]bool)
+	for _, p := range g.Projects {
+		local[p.Name] = true
+		local[p.PackageName()] = true
+	}
+
+	var public []string
+	for _, field := range []string{"dependencies", "devDependencies"} {
+		for dep, version := range g.Projects[name].Dependencies(field) {
+			if !local[dep] && !isAPrivateDependency(version, exclude...) {
+				public = append(public, dep)
+			}
+		}
+	}
+
+	sort.Strings(public)
+	return dedupe(public)
+}
+
+func dedupe(sorted []string) []string {
+	var unique []string
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			unique = append(unique, s)
+		}
+	}
+
+	return unique
+}
+
+func (g *ProjectGraph) Dot(public map[string][]string) []byte {
+	var b bytes.Buffer
+	b.WriteString("digraph triforce {\n")
+
+	for _, name := range g.Names() {
+		fmt.Fprintf(&b, "  %q [shape=box];\n", name)
+	}
+
+	for _, name := range g.Names() {
+		for _, dep := range g.Dependencies[name] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", name, dep)
+		}
+
+		for _, dep := range public[name] {
+			fmt.Fprintf(&b, "  %q -> %q [style=dashed];\n", name, dep)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func (g *ProjectGraph) Mermaid(public map[string][]string) []byte {
+	id := func(prefix, name string) string {
+		return prefix + mermaidUnsafe.ReplaceAllString(name, "_")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("graph TD\n")
+
+	for _, name := range g.Names() {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id("local_", name), name)
+	}
+
+	for _, name := range g.Names() {
+		for _, dep := range g.Dependencies[name] {
+			fmt.Fprintf(&b, "  %s --> %s\n", id("local_", name), id("local_", dep))
+		}
+
+		for _, dep := range public[name] {
+			fmt.Fprintf(&b, "  %s -.-> %s(\"%s\")\n", id("local_", name), id("public_", dep), dep)
+		}
+	}
+
+	return b.Bytes()
+}
+
+type GraphJSON struct {
+	Projects           []string            `json:"projects"`
+	Dependencies       map[string][]string `json:"dependencies"`
+	PublicDependencies map[string][]string `json:"publicDependencies,omitempty"`
+}
+
+func (g *ProjectGraph) JSON(public map[string][]string) ([]byte, error) {
+	graph := GraphJSON{
+		Projects:     g.Names(),
+		Dependencies: make(map[string][]string),
+	}
+
+	for _, name := range g.Names() {
+		graph.Dependencies[name] = append([]string{}, g.Dependencies[name]...)
+	}
+
+	if len(public) > 0 {
+		graph.PublicDependencies = make(map[string][]string)
+		for name, deps := range public {
+			graph.PublicDependencies[name] = append([]string{}, deps...)
+		}
+	}
+
+	return json.MarshalIndent(graph, "", "  ")
+}
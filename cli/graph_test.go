@@ -0,0 +1,124 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type GraphJSON struct {
+	Projects           []string            `json:"projects"`
+	Dependencies       map[string][]string `json:"dependencies"`
+	PublicDependencies map[string][]string `json:"publicDependencies"`
+}
+
+var _ = Describe("Graph", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+
+	BeforeEach(func() {
+		p = make(map[string]*BasicPackageJSON)
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	Context("sanity checking", func() {
+		It("should throw an error if trying to graph without enough args", func() {
+			args := []string{"triforce", "graph"}
+			Expect(cli.App().Run(args)).NotTo(Succeed())
+		})
+
+		It("should throw an error if given an unknown format", func() {
+			p["project-1"] = NewBasicPackageJSONBuilder().Build()
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "graph", "--format", "svg", t.RootFolder}
+			Expect(cli.App().Run(args)).NotTo(Succeed())
+		})
+	})
+
+	Context("projects depending on other local projects", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().
+				Dependency("lib-1", "github:someorg/lib-1#v1.0.0").
+				Dependency("dep-a", "1.0.0").
+				Dependency("dep-b", "1.0.0").
+				Build()
+
+			p["lib-1"] = NewBasicPackageJSONBuilder().
+				Name("@someorg/lib-1").
+				DevDependency("lib-2", "^1.0.0").
+				Build()
+
+			p["lib-2"] = NewBasicPackageJSONBuilder().Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should export adjacency lists as json matching names and private repository urls", func() {
+			output := filepath.Join(t.RootFolder, "graph.json")
+			args := []string{"triforce", "graph", "--format", "json", "--output", output, t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			graph := GraphJSON{}
+			Expect(json.Unmarshal(bytes, &graph)).To(Succeed())
+
+			Expect(graph.Projects).To(Equal([]string{"api-1", "lib-1", "lib-2"}))
+			Expect(graph.Dependencies["api-1"]).To(Equal([]string{"lib-1"}))
+			Expect(graph.Dependencies["lib-1"]).To(Equal([]string{"lib-2"}))
+			Expect(graph.Dependencies["lib-2"]).To(BeEmpty())
+			Expect(graph.PublicDependencies).To(BeEmpty())
+		})
+
+		It("should include public dependencies up to the given limit", func() {
+			output := filepath.Join(t.RootFolder, "graph.json")
+			args := []string{"triforce", "graph", "--format", "json", "--public", "--public-limit", "1", "--output", output, t.RootFolder}
+			Expect(cli.App().Run(args)).To(Succeed())
+
+			bytes, err := ioutil.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			graph := GraphJSON{}
+			Expect(json.Unmarshal(bytes, &graph)).To(Succeed())
+
+			Expect(graph.PublicDependencies["api-1"]).To(Equal([]string{"dep-a"}))
+		})
+
+		It("should export the graph as dot", func() {
+			var out bytes.Buffer
+			app := cli.App()
+			app.Writer = &out
+
+			args := []string{"triforce", "graph", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			Expect(out.String()).To(HavePrefix("digraph triforce {\n"))
+			Expect(out.String()).To(ContainSubstring(`"api-1" -> "lib-1";`))
+			Expect(out.String()).To(ContainSubstring(`"lib-1" -> "lib-2";`))
+		})
+
+		It("should export the graph as mermaid", func() {
+			var out bytes.Buffer
+			app := cli.App()
+			app.Writer = &out
+
+			args := []string{"triforce", "graph", "--format", "mermaid", "--public", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			Expect(out.String()).To(HavePrefix("graph TD\n"))
+			Expect(out.String()).To(ContainSubstring(`local_api_1 --> local_lib_1`))
+			Expect(out.String()).To(ContainSubstring(`local_api_1 -.-> public_dep_a("dep-a")`))
+		})
+	})
+})
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// InstallRecord is written to the node_modules folder after a successful install, holding the snapshot key
+// of the assembled package.json file that was installed
+const InstallRecord = ".triforce-install"
+
+func Install() cli.Command {
+	return cli.Command{
+		Name:      "install",
+		ShortName: "i",
+		Usage:     "assembles a package.json file, installs it and links private projects",
+		UsageText: "triforce install [command options] root [-- arguments...]",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "since, s", Usage: "only include projects affected by changes since this git ref"},
+			cli.BoolFlag{Name: "groups, g", Usage: "assemble, install and link a package.json file for each group defined in .triforce.json"},
+			cli.BoolFlag{Name: "locked", Usage: "use the versions locked in the package-lock.json or yarn.lock file of each project when they satisfy every range"},
+			cli.StringFlag{Name: "npm-client", Usage: "package manager used to install the assembled package.json file (npm, yarn or pnpm, defaults to the install client in .triforce.json or npm)"},
+			cli.BoolFlag{Name: "force", Usage: "install even if the assembled package.json file has not changed since the last successful install"},
+			cli.StringFlag{Name: "on-collision", Usage: "what to do when a package that is not a link is installed under the name of a project: fail, skip or replace (moving it to .triforce-backup)", Value: collisionFail},
+		},
+		Action: func(c *cli.Context) error {
+			args := c.Args()
+			if len(args) > 1 && args[1] == "--" {
+				args = append(cli.Args{args[0]}, args[2:]...)
+			}
+
+			if len(args) < 1 {
+				return fmt.Errorf("triforce install requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(args.First())
+			if err != nil {
+				return err
+			}
+
+			config, err := loadConfig(root)
+			if err != nil {
+				return err
+			}
+
+			client := c.String("npm-client")
+			if client == "" {
+				client = config.Install.Client
+			}
+
+			if client == "" {
+				client = "npm"
+			}
+
+			installArgs := []string(args.Tail())
+			if len(installArgs) == 0 {
+				installArgs = config.Install.Args
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			projectFolders, err := selectProjectFolders(root, c.StringSlice("filter"), c.String("since"), exclude, c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, projectFolders)
+			if err != nil {
+				return err
+			}
+
+			// the package.json file is installed where triforce assemble writes it
+			var targets []manifestTarget
+			if c.Bool("groups") {
+				if targets, err = groupTargets(root, projects); err != nil {
+					return err
+				}
+			} else {
+				directory, err := filepath.Abs(".")
+				if err != nil {
+					return err
+				}
+
+				targets = []manifestTarget{{Name: fmt.Sprintf("triforce-%s", filepath.Base(root)), Directory: directory, Projects: projects}}
+			}
+
+			plan := &LinkPlan{Root: root}
+			for _, target := range targets {
+				if target.Group != "" {
+					color.Cyan("\ninstalling group %s", target.Group)
+				}
+
+				t, err := assembleManifest(target.Name, target.Projects, exclude, c.Bool("locked"))
+				if err != nil {
+					return err
+				}
+
+				if err := installManifest(c, client, installArgs, target.Directory, t); err != nil {
+					return err
+				}
+
+				if err := plan.Add(target.Directory, target.Projects, c.String("on-collision")); err != nil {
+					return err
+				}
+			}
+
+			if err := plan.Apply(); err != nil {
+				return err
+			}
+
+			for _, target := range targets {
+				plan.Print(c.App.Writer, target.Directory)
+			}
+
+			return reportCollisions(c, root, plan.Collisions)
+		},
+	}
+}
+
+// installManifest writes an assembled package.json file to a directory and installs it there, unless it has
+// not changed since the last successful install, putting back the previous package.json file if it fails
+func installManifest(c *cli.Context, client string, installArgs []string, directory string, t TriforcePackageJSON) error {
+	if err := os.MkdirAll(directory, os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	manifestPath := filepath.Join(directory, PackageJSON)
+	previous, err := ioutil.ReadFile(manifestPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := writePackageJSON(manifestPath, t); err != nil {
+		return err
+	}
+
+	key, err := snapshotKey(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	record := filepath.Join(directory, NodeModules, InstallRecord)
+	if installed, err := ioutil.ReadFile(record); err == nil && strings.TrimSpace(string(installed)) == key && !c.Bool("force") {
+		color.New(color.FgGreen).Fprintf(c.App.Writer, "\nskipping %s install (%s has not changed since the last successful install)\n", client, PackageJSON)
+		return nil
+	}
+
+	command := append([]string{client, "install"}, installArgs...)
+	color.New(color.FgGreen).Fprintf(c.App.Writer, "\nrunning %s\n", strings.Join(command, " "))
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = directory
+	cmd.Stdout = c.App.Writer
+	cmd.Stderr = c.App.ErrWriter
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		if rollbackErr := restoreManifest(manifestPath, previous); rollbackErr != nil {
+			return fmt.Errorf("%s install failed (%s) and the previous %s could not be restored: %s", client, err, PackageJSON, rollbackErr)
+		}
+
+		return fmt.Errorf("%s install failed, restored the previous %s: %s", client, PackageJSON, err)
+	}
+
+	return ioutil.WriteFile(record, []byte(key+"\n"), os.FileMode(0666))
+}
+
+// restoreManifest puts back the package.json file that was at the root before it was assembled, removing
+// the assembled package.json file if there was none
+func restoreManifest(manifestPath string, previous []byte) error {
+	if previous == nil {
+		return os.Remove(manifestPath)
+	}
+
+	return ioutil.WriteFile(manifestPath, previous, os.FileMode(0666))
+}
@@ -0,0 +1,170 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Install", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+	var npmClient string
+	var wd string
+
+	writeClient := func(script string) {
+		npmClient, err = filepath.Abs(filepath.Join(t.RootFolder, "fake-npm"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(npmClient, []byte(script), os.FileMode(0755))).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["api-1"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.0.0").
+			Dependency("lib-1", "github:someorg/lib-1").
+			Build()
+
+		p["lib-1"] = NewBasicPackageJSONBuilder().Dependency("dep-b", "^2.0.0").Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		// like assemble, install writes the package.json file to the working directory
+		t.RootFolder, err = filepath.Abs(t.RootFolder)
+		Expect(err).NotTo(HaveOccurred())
+		wd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(t.RootFolder)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(wd)).To(Succeed())
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	Context("a successful install", func() {
+		BeforeEach(func() {
+			// a stand-in for npm that records the arguments it was run with
+			writeClient("#!/bin/sh\necho \"installing $@\"\necho \"$@\" >> runs.txt\n")
+		})
+
+		It("should assemble, install and link in the root folder", func() {
+			args := []string{"triforce", "install", "--npm-client", npmClient, t.RootFolder, "--", "--no-audit"}
+			Expect(app.Run(args)).To(Succeed())
+
+			runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runs)).To(Equal("install --no-audit\n"))
+			Expect(out.String()).To(ContainSubstring("installing install --no-audit"))
+
+			_, err = os.Stat(filepath.Join(t.RootFolder, "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			link, err := os.Readlink(filepath.Join(t.RootFolder, "node_modules", "lib-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("../lib-1"))
+		})
+
+		It("should skip the install if the assembled package.json file has not changed", func() {
+			args := []string{"triforce", "install", "--npm-client", npmClient, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(app.Run(args)).To(Succeed())
+
+			runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runs)).To(Equal("install\n"))
+			Expect(out.String()).To(ContainSubstring("skipping"))
+
+			By("installing again when forced", func() {
+				args := []string{"triforce", "install", "--force", "--npm-client", npmClient, t.RootFolder}
+				Expect(app.Run(args)).To(Succeed())
+
+				runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(runs)).To(Equal("install\ninstall\n"))
+			})
+		})
+
+		It("should use the install client and arguments from .triforce.json", func() {
+			Expect(t.WriteConfig(`{"install": {"client": "` + npmClient + `", "args": ["--prefer-offline"]}}`)).To(Succeed())
+
+			args := []string{"triforce", "install", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runs)).To(Equal("install --prefer-offline\n"))
+		})
+	})
+
+	Context("a successful install with link options", func() {
+		BeforeEach(func() {
+			writeClient("#!/bin/sh\nmkdir -p node_modules\necho \"$PWD\" >> \"$(dirname \"$0\")/runs.txt\"\n")
+		})
+
+		It("should assemble, install and link each group in its own directory", func() {
+			Expect(t.WriteConfig(groupsConfig)).To(Succeed())
+
+			args := []string{"triforce", "install", "--groups", "--npm-client", npmClient, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runs)).To(Equal(filepath.Join(t.RootFolder, "apis") + "\n" + filepath.Join(t.RootFolder, "apps") + "\n"))
+
+			Expect(filepath.Join(t.RootFolder, "apis", "package.json")).To(BeAnExistingFile())
+			link, err := os.Readlink(filepath.Join(t.RootFolder, "apis", "node_modules", "api-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("../../api-1"))
+		})
+
+		It("should handle packages in the way of links according to the collision policy", func() {
+			Expect(t.Install("lib-1", "1.0.0")).To(Succeed())
+
+			args := []string{"triforce", "install", "--npm-client", npmClient, t.RootFolder}
+			Expect(app.Run(args)).To(MatchError(ContainSubstring("found packages that are not links")))
+
+			args = []string{"triforce", "install", "--on-collision", "replace", "--npm-client", npmClient, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			link, err := os.Readlink(filepath.Join(t.RootFolder, "node_modules", "lib-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("../lib-1"))
+			Expect(filepath.Join(t.RootFolder, ".triforce-backup", "lib-1", "package.json")).To(BeAnExistingFile())
+		})
+	})
+
+	Context("a failed install", func() {
+		It("should restore the previous package.json file and not link", func() {
+			writeClient("#!/bin/sh\nexit 1\n")
+
+			previous := NewBasicPackageJSONBuilder().Name("previous").Build()
+			Expect(t.WriteManifest(previous)).To(Succeed())
+			before, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "install", "--npm-client", npmClient, t.RootFolder}
+			Expect(app.Run(args)).NotTo(Succeed())
+
+			after, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(after).To(Equal(before))
+
+			_, err = os.Lstat(filepath.Join(t.RootFolder, "node_modules", "lib-1"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
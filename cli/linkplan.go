@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+)
+
+// LinkPlan lists every link to be made into node_modules folders, so that nothing is changed until every
+// collision has been checked and every change that is made can be undone if a later one fails
+type LinkPlan struct {
+	Root       string
+	Steps      []*LinkStep
+	Collisions []*Collision
+}
+
+// LinkStep links a project into a node_modules folder, first moving the package in its way to Backup if
+// there is one
+type LinkStep struct {
+	Project   string
+	Directory string
+	Path      string
+	Target    string
+	Backup    string
+}
+
+// linkJournal records how to undo every change made while applying a plan, along with the earlier backups
+// replaced by newer ones, which are only removed once every link has been made
+type linkJournal struct {
+	undo    []func() error
+	discard []string
+}
+
+// Add plans the links of projects into the node_modules folder of a directory, handling packages that are
+// already installed under the name of a project according to the collision policy
+func (plan *LinkPlan) Add(directory string, projects []*Project, onCollision string) error {
+	nodeModules := filepath.Join(directory, NodeModules)
+	if _, err := os.Stat(nodeModules); err != nil {
+		return fmt.Errorf("no node_modules folder found at %s", directory)
+	}
+
+	collisions := findCollisions(nodeModules, projects)
+	backups := make(map[string]string)
+
+	switch onCollision {
+	case collisionFail:
+		// nothing is linked if anything is in the way, so that linking never stops half-way
+		if len(collisions) > 0 {
+			var names []string
+			for _, collision := range collisions {
+				names = append(names, collision.Project)
+			}
+
+			return fmt.Errorf("found packages that are not links in %s: %s (use --on-collision=skip or --on-collision=replace)", relativeTo(plan.Root, nodeModules), strings.Join(names, ", "))
+		}
+	case collisionSkip:
+		for _, collision := range collisions {
+			collision.Action = collisionSkip
+			backups[collision.Project] = ""
+		}
+	case collisionReplace:
+		for _, collision := range collisions {
+			collision.Action = collisionReplace
+			collision.Backup = filepath.Join(directory, BackupFolder, collision.Project)
+			backups[collision.Project] = collision.Backup
+		}
+	default:
+		return fmt.Errorf("unknown collision policy \"%s\" (use %s, %s or %s)", onCollision, collisionFail, collisionSkip, collisionReplace)
+	}
+
+	for _, p := range projects {
+		backup, collided := backups[p.Name]
+		if collided && backup == "" {
+			continue
+		}
+
+		target, err := filepath.Rel(nodeModules, p.Path)
+		if err != nil {
+			return err
+		}
+
+		plan.Steps = append(plan.Steps, &LinkStep{
+			Project:   p.Name,
+			Directory: directory,
+			Path:      filepath.Join(nodeModules, p.Name),
+			Target:    target,
+			Backup:    backup,
+		})
+	}
+
+	plan.Collisions = append(plan.Collisions, collisions...)
+	return nil
+}
+
+// Apply makes the planned changes, undoing all of them if any change fails or the process is interrupted, so
+// that every node_modules folder is left either as it was or fully linked
+func (plan *LinkPlan) Apply() error {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	journal := &linkJournal{}
+	for _, step := range plan.Steps {
+		select {
+		case sig := <-interrupted:
+			return journal.rollback(fmt.Errorf("linking was interrupted by %s", sig))
+		default:
+		}
+
+		if err := journal.apply(step); err != nil {
+			return journal.rollback(fmt.Errorf("could not link %s into %s: %s", step.Project, relativeTo(plan.Root, filepath.Dir(step.Path)), err))
+		}
+	}
+
+	// an interrupt received while making the last link still undoes every link
+	select {
+	case sig := <-interrupted:
+		return journal.rollback(fmt.Errorf("linking was interrupted by %s", sig))
+	default:
+	}
+
+	return journal.commit()
+}
+
+// Print lists the links that were made into the node_modules folder of a directory
+func (plan *LinkPlan) Print(w io.Writer, directory string) {
+	relativeNodeModules := relativeTo(plan.Root, filepath.Join(directory, NodeModules))
+	for _, step := range plan.Steps {
+		if step.Directory == directory {
+			fmt.Fprintf(w, "symlinked %s to %s/%s\n", step.Project, relativeNodeModules, step.Project)
+		}
+	}
+
+	color.New(color.FgGreen).Fprintf(w, "finished linking private dependencies to %s\n", relativeNodeModules)
+}
+
+func (j *linkJournal) apply(step *LinkStep) error {
+	if step.Backup != "" {
+		if err := j.backup(step.Path, step.Backup); err != nil {
+			return err
+		}
+	} else if info, err := os.Lstat(step.Path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		// remove symlinks if they already exist
+		target, err := os.Readlink(step.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(step.Path); err != nil {
+			return err
+		}
+
+		j.undo = append(j.undo, func() error { return os.Symlink(target, step.Path) })
+	}
+
+	if err := os.Symlink(step.Target, step.Path); err != nil {
+		return err
+	}
+
+	j.undo = append(j.undo, func() error { return os.Remove(step.Path) })
+	return nil
+}
+
+// backup moves a package out of the way of a link into the backup folder next to the node_modules folder,
+// setting aside any earlier backup of the same package to be removed once every link has been made
+func (j *linkJournal) backup(path, backup string) error {
+	folder := filepath.Dir(backup)
+	if _, err := os.Stat(folder); os.IsNotExist(err) {
+		if err := os.Mkdir(folder, os.FileMode(0755)); err != nil {
+			return err
+		}
+
+		j.undo = append(j.undo, func() error { return os.Remove(folder) })
+	}
+
+	if _, err := os.Lstat(backup); err == nil {
+		replaced, err := ioutil.TempDir(folder, ".replaced")
+		if err != nil {
+			return err
+		}
+
+		j.undo = append(j.undo, func() error { return os.Remove(replaced) })
+		if err := j.rename(backup, filepath.Join(replaced, filepath.Base(backup))); err != nil {
+			return err
+		}
+
+		j.discard = append(j.discard, replaced)
+	}
+
+	return j.rename(path, backup)
+}
+
+func (j *linkJournal) rename(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+
+	j.undo = append(j.undo, func() error { return os.Rename(to, from) })
+	return nil
+}
+
+// rollback undoes every change in reverse order, carrying on past changes that cannot be undone so that as
+// much as possible is put back
+func (j *linkJournal) rollback(cause error) error {
+	var failed []string
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		if err := j.undo[i](); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%s, and %d change(s) could not be rolled back: %s", cause, len(failed), strings.Join(failed, "; "))
+	}
+
+	return fmt.Errorf("%s, rolled back all changes", cause)
+}
+
+func (j *linkJournal) commit() error {
+	for _, replaced := range j.discard {
+		if err := os.RemoveAll(replaced); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,373 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// PackageLockJSON is a package-lock.json file in the flat packages format, where every installed package is
+// keyed by its path relative to the root, such as "node_modules/a/node_modules/b"
+type PackageLockJSON struct {
+	Name            string                            `json:"name"`
+	LockfileVersion int                               `json:"lockfileVersion"`
+	Requires        bool                              `json:"requires"`
+	Packages        map[string]map[string]interface{} `json:"packages"`
+}
+
+func Lock() cli.Command {
+	return cli.Command{
+		Name:  "lock",
+		Usage: "merges the package-lock.json file of each project into a package-lock.json file for the assembled package.json file",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "manifest, m", Usage: "assembled package.json file to lock (defaults to the package.json file at the root)"},
+			cli.StringFlag{Name: "output, o", Usage: "file to write the merged lockfile to (defaults to the package-lock.json file at the root)"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce lock requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			manifestPath := c.String("manifest")
+			if manifestPath == "" {
+				manifestPath = filepath.Join(root, PackageJSON)
+			}
+
+			output := c.String("output")
+			if output == "" {
+				output = filepath.Join(root, PackageLock)
+			}
+
+			manifest, err := gabs.ParseJSONFile(manifestPath)
+			if err != nil {
+				return err
+			}
+
+			projectFolders, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, projectFolders)
+			if err != nil {
+				return err
+			}
+
+			locks := make(map[string]map[string]map[string]interface{})
+			for _, p := range projects {
+				packages, err := readPackageLock(filepath.Join(p.Path, PackageLock))
+				if err != nil {
+					if os.IsNotExist(err) {
+						color.New(color.FgYellow).Fprintf(c.App.Writer, "skipping %s (no %s file)\n", p.Name, PackageLock)
+						continue
+					}
+
+					return err
+				}
+
+				locks[p.Name] = packages
+			}
+
+			dependencies := stringValues(manifest.Path("dependencies").Data())
+			devDependencies := stringValues(manifest.Path("devDependencies").Data())
+
+			merge := newLockMerge(locks)
+			for _, dep := range sortedKeys(dependencies) {
+				merge.Add(dep, dependencies[dep])
+			}
+
+			for _, dep := range sortedKeys(devDependencies) {
+				merge.Add(dep, devDependencies[dep])
+			}
+
+			merge.MarkDev(sortedKeys(dependencies))
+
+			name, _ := manifest.Path("name").Data().(string)
+			merge.Packages[""] = map[string]interface{}{"name": name, "dependencies": dependencies, "devDependencies": devDependencies}
+
+			bytes, err := json.MarshalIndent(PackageLockJSON{Name: name, LockfileVersion: 3, Requires: true, Packages: merge.Packages}, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(output, append(bytes, '\n'), os.FileMode(0666)); err != nil {
+				return err
+			}
+
+			for _, problem := range merge.Dropped {
+				color.New(color.FgYellow).Fprintln(c.App.Writer, problem)
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\nmerged %d package(s) from %d lockfile(s) into %s\n", len(merge.Packages)-1, len(locks), output)
+
+			if len(merge.Dropped) > 0 {
+				fmt.Fprintf(c.App.Writer, "dropped %d subtree(s), run \"npm install --package-lock-only\" to resolve them before using \"npm ci\"\n", len(merge.Dropped))
+			}
+
+			return nil
+		},
+	}
+}
+
+// readPackageLock reads the packages of a package-lock.json file, converting the nested dependencies of
+// lockfile version 1 into the flat packages format of lockfile version 2 and above
+func readPackageLock(file string) (map[string]map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock struct {
+		Packages     map[string]map[string]interface{} `json:"packages"`
+		Dependencies map[string]interface{}            `json:"dependencies"`
+	}
+
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", file, err)
+	}
+
+	if lock.Packages != nil {
+		delete(lock.Packages, "")
+		return lock.Packages, nil
+	}
+
+	packages := make(map[string]map[string]interface{})
+	flattenDependencies("", lock.Dependencies, packages)
+	return packages, nil
+}
+
+func flattenDependencies(parent string, dependencies map[string]interface{}, packages map[string]map[string]interface{}) {
+	for name, value := range dependencies {
+		dep, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path := lockPath(parent, name)
+		entry := make(map[string]interface{})
+		for _, field := range []string{"version", "resolved", "integrity", "dev", "optional"} {
+			if v, ok := dep[field]; ok {
+				entry[field] = v
+			}
+		}
+
+		if requires, ok := dep["requires"].(map[string]interface{}); ok {
+			entry["dependencies"] = requires
+		}
+
+		packages[path] = entry
+
+		if nested, ok := dep["dependencies"].(map[string]interface{}); ok {
+			flattenDependencies(path, nested, packages)
+		}
+	}
+}
+
+func lockPath(parent, name string) string {
+	if parent == "" {
+		return NodeModules + "/" + name
+	}
+
+	return parent + "/" + NodeModules + "/" + name
+}
+
+// resolveLocked finds the path of the package that node would resolve from the package at the given path,
+// looking in its own node_modules folder before those of its parents
+func resolveLocked(packages map[string]map[string]interface{}, from, name string) (string, bool) {
+	for dir := from; ; {
+		if _, ok := packages[lockPath(dir, name)]; ok {
+			return lockPath(dir, name), true
+		}
+
+		if dir == "" {
+			return "", false
+		}
+
+		dir = strings.TrimSuffix(dir[:strings.LastIndex(dir, NodeModules+"/")], "/")
+	}
+}
+
+// lockClosure returns the paths of a package and everything it resolves to within a lockfile, skipping
+// linked packages which belong to the project that was locked
+func lockClosure(packages map[string]map[string]interface{}, start string) []string {
+	var closure []string
+	seen := map[string]bool{start: true}
+
+	for queue := []string{start}; len(queue) > 0; queue = queue[1:] {
+		path := queue[0]
+		if linked, _ := packages[path]["link"].(bool); linked {
+			continue
+		}
+
+		closure = append(closure, path)
+
+		for _, field := range []string{"dependencies", "optionalDependencies"} {
+			for name := range stringValues(packages[path][field]) {
+				if resolved, ok := resolveLocked(packages, path, name); ok && !seen[resolved] {
+					seen[resolved] = true
+					queue = append(queue, resolved)
+				}
+			}
+		}
+	}
+
+	sort.Strings(closure)
+	return closure
+}
+
+type lockMerge struct {
+	Locks    map[string]map[string]map[string]interface{}
+	Packages map[string]map[string]interface{}
+	Dropped  []string
+	origins  map[string]string
+}
+
+func newLockMerge(locks map[string]map[string]map[string]interface{}) *lockMerge {
+	return &lockMerge{
+		Locks:    locks,
+		Packages: make(map[string]map[string]interface{}),
+		origins:  make(map[string]string),
+	}
+}
+
+// Add merges the subtree of a dependency from the lockfile of the project with the highest locked version
+// satisfying its range, falling back to the next candidate if the subtree conflicts with packages that have
+// already been merged, and dropping the dependency if no candidate can be merged
+func (m *lockMerge) Add(dep, version string) {
+	candidates := m.candidates(dep, version)
+	if len(candidates) == 0 {
+		m.Dropped = append(m.Dropped, fmt.Sprintf("dropped \"%s\" (no project lockfile has a version satisfying \"%s\")", dep, version))
+		return
+	}
+
+	var conflict string
+	for _, project := range candidates {
+		packages := m.Locks[project]
+		closure := lockClosure(packages, lockPath("", dep))
+
+		conflict = ""
+		for _, path := range closure {
+			existing, ok := m.Packages[path]
+			if ok && existing["version"] != packages[path]["version"] {
+				conflict = fmt.Sprintf("%s with version \"%v\" from %s conflicts with version \"%v\" from %s", path, packages[path]["version"], project, existing["version"], m.origins[path])
+				break
+			}
+		}
+
+		if conflict != "" {
+			continue
+		}
+
+		for _, path := range closure {
+			if _, ok := m.Packages[path]; !ok {
+				m.Packages[path] = copyEntry(packages[path])
+				m.origins[path] = project
+			}
+		}
+
+		return
+	}
+
+	m.Dropped = append(m.Dropped, fmt.Sprintf("dropped \"%s\" subtree (%s)", dep, conflict))
+}
+
+// candidates returns the projects with a locked version of a dependency that satisfies its range, with the
+// highest version first
+func (m *lockMerge) candidates(dep, version string) []string {
+	type candidate struct {
+		project string
+		version Version
+	}
+
+	var cs []candidate
+	for project, packages := range m.Locks {
+		locked, _ := packages[lockPath("", dep)]["version"].(string)
+		if ok, err := satisfies(locked, version); err != nil || !ok {
+			continue
+		}
+
+		v, _ := parseVersion(locked)
+		cs = append(cs, candidate{project: project, version: v})
+	}
+
+	sort.Slice(cs, func(i, j int) bool {
+		if c := cs[i].version.Compare(cs[j].version); c != 0 {
+			return c > 0
+		}
+
+		return cs[i].project < cs[j].project
+	})
+
+	var projects []string
+	for _, c := range cs {
+		projects = append(projects, c.project)
+	}
+
+	return projects
+}
+
+// MarkDev marks every merged package that is not reachable from the given dependencies as a dev package
+func (m *lockMerge) MarkDev(dependencies []string) {
+	production := make(map[string]bool)
+	for _, dep := range dependencies {
+		if _, ok := m.Packages[lockPath("", dep)]; ok {
+			for _, path := range lockClosure(m.Packages, lockPath("", dep)) {
+				production[path] = true
+			}
+		}
+	}
+
+	for path, entry := range m.Packages {
+		if production[path] {
+			delete(entry, "dev")
+		} else {
+			entry["dev"] = true
+		}
+	}
+}
+
+func copyEntry(entry map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{})
+	for k, v := range entry {
+		copied[k] = v
+	}
+
+	return copied
+}
+
+func stringValues(data interface{}) map[string]string {
+	values := make(map[string]string)
+	if m, ok := data.(map[string]interface{}); ok {
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				values[k] = s
+			}
+		}
+	}
+
+	return values
+}
+
+func sortedKeys(values map[string]string) []string {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
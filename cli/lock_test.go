@@ -0,0 +1,145 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const project1Lock = `{
+  "name": "project-1",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "project-1"},
+    "node_modules/dep-a": {"version": "1.2.0", "dependencies": {"dep-c": "^2.0.0"}},
+    "node_modules/dep-c": {"version": "2.1.0"},
+    "node_modules/lib-1": {"resolved": "../lib-1", "link": true}
+  }
+}`
+
+const project2Lock = `{
+  "name": "project-2",
+  "lockfileVersion": 1,
+  "dependencies": {
+    "dep-b": {
+      "version": "3.0.5",
+      "dev": true,
+      "requires": {"dep-c": "^1.0.0"},
+      "dependencies": {
+        "dep-c": {"version": "1.0.0", "dev": true}
+      }
+    }
+  }
+}`
+
+const project3Lock = `{
+  "name": "project-3",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "project-3"},
+    "node_modules/dep-d": {"version": "1.0.0", "dependencies": {"dep-c": "^1.0.0"}},
+    "node_modules/dep-c": {"version": "1.0.0"}
+  }
+}`
+
+var _ = Describe("Lock", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	writeLock := func(project, lock string) {
+		Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, project, "package-lock.json"), []byte(lock), os.FileMode(0666))).To(Succeed())
+	}
+
+	readLock := func() map[string]map[string]interface{} {
+		bytes, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "package-lock.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		lock := struct {
+			LockfileVersion int                               `json:"lockfileVersion"`
+			Packages        map[string]map[string]interface{} `json:"packages"`
+		}{}
+
+		Expect(json.Unmarshal(bytes, &lock)).To(Succeed())
+		Expect(lock.LockfileVersion).To(Equal(3))
+		return lock.Packages
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["project-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.0.0").Dependency("lib-1", "github:someorg/lib-1").Build()
+		p["project-2"] = NewBasicPackageJSONBuilder().DevDependency("dep-b", "~3.0.0").Build()
+		p["project-3"] = NewBasicPackageJSONBuilder().Dependency("dep-d", "^1.0.0").Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		writeLock("project-1", project1Lock)
+		writeLock("project-2", project2Lock)
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should merge the subtree of each dependency from the project lockfiles", func() {
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().
+			Name("triforce-ginkgo_tests").
+			Dependency("dep-a", "^1.0.0").
+			DevDependency("dep-b", "~3.0.0").
+			Build())).To(Succeed())
+
+		args := []string{"triforce", "lock", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		packages := readLock()
+		Expect(packages).To(HaveLen(5))
+		Expect(packages[""]).To(HaveKeyWithValue("name", "triforce-ginkgo_tests"))
+
+		Expect(packages["node_modules/dep-a"]).To(HaveKeyWithValue("version", "1.2.0"))
+		Expect(packages["node_modules/dep-a"]).NotTo(HaveKey("dev"))
+		Expect(packages["node_modules/dep-c"]).To(HaveKeyWithValue("version", "2.1.0"))
+		Expect(packages["node_modules/dep-c"]).NotTo(HaveKey("dev"))
+
+		Expect(packages["node_modules/dep-b"]).To(HaveKeyWithValue("version", "3.0.5"))
+		Expect(packages["node_modules/dep-b"]).To(HaveKeyWithValue("dev", true))
+		Expect(packages["node_modules/dep-b/node_modules/dep-c"]).To(HaveKeyWithValue("version", "1.0.0"))
+
+		Expect(out.String()).To(ContainSubstring("skipping project-3 (no package-lock.json file)"))
+		Expect(out.String()).NotTo(ContainSubstring("dropped"))
+	})
+
+	It("should drop the subtrees that conflict with packages that have already been merged", func() {
+		writeLock("project-3", project3Lock)
+
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.0.0").
+			Dependency("dep-d", "^1.0.0").
+			DevDependency("dep-e", "^1.0.0").
+			Build())).To(Succeed())
+
+		args := []string{"triforce", "lock", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		packages := readLock()
+		Expect(packages).To(HaveKey("node_modules/dep-a"))
+		Expect(packages).NotTo(HaveKey("node_modules/dep-d"))
+		Expect(packages["node_modules/dep-c"]).To(HaveKeyWithValue("version", "2.1.0"))
+
+		Expect(out.String()).To(ContainSubstring(`dropped "dep-d" subtree (node_modules/dep-c with version "1.0.0" from project-3 conflicts with version "2.1.0" from project-1)`))
+		Expect(out.String()).To(ContainSubstring(`dropped "dep-e" (no project lockfile has a version satisfying "^1.0.0")`))
+		Expect(out.String()).To(ContainSubstring("dropped 2 subtree(s)"))
+	})
+})
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/fatih/color"
+)
+
+const PackageLock = "package-lock.json"
+const YarnLock = "yarn.lock"
+
+// readLockedVersions reads the version locked for each dependency of a project from its package-lock.json
+// file, or its yarn.lock file if it has no package-lock.json file, returning nil if it has neither
+func readLockedVersions(p *Project) (map[string]string, error) {
+	if parsed, err := gabs.ParseJSONFile(filepath.Join(p.Path, PackageLock)); err == nil {
+		return packageLockVersions(p, parsed), nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not parse %s: %s", filepath.Join(p.Path, PackageLock), err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(p.Path, YarnLock))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return yarnLockVersions(p, parseYarnLock(content)), nil
+}
+
+// packageLockVersions reads the versions installed at the top level of a project from a package-lock.json
+// file, using the packages section of lockfile version 2 and above before the dependencies section
+func packageLockVersions(p *Project, parsed *gabs.Container) map[string]string {
+	locked := make(map[string]string)
+
+	for _, field := range []string{"dependencies", "devDependencies"} {
+		for dep := range p.Dependencies(field) {
+			if version, ok := parsed.Search("packages", "node_modules/"+dep, "version").Data().(string); ok {
+				locked[dep] = version
+				continue
+			}
+
+			if version, ok := parsed.Search("dependencies", dep, "version").Data().(string); ok {
+				locked[dep] = version
+			}
+		}
+	}
+
+	return locked
+}
+
+// yarnLockVersions looks up the version locked for the range that each dependency of a project declares
+func yarnLockVersions(p *Project, entries map[string]string) map[string]string {
+	locked := make(map[string]string)
+
+	for _, field := range []string{"dependencies", "devDependencies"} {
+		for dep, version := range p.Dependencies(field) {
+			if v, ok := entries[dep+"@"+version]; ok {
+				locked[dep] = v
+			}
+		}
+	}
+
+	return locked
+}
+
+// parseYarnLock maps every "name@range" specifier in a yarn.lock file to the version it resolved to,
+// reading both the classic format and the YAML format of yarn 2 and above
+func parseYarnLock(content []byte) map[string]string {
+	entries := make(map[string]string)
+
+	var specifiers []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			specifiers = nil
+			if strings.HasSuffix(trimmed, ":") {
+				for _, s := range strings.Split(strings.TrimSuffix(trimmed, ":"), ",") {
+					specifiers = append(specifiers, normaliseSpecifier(strings.Trim(strings.TrimSpace(s), `"`)))
+				}
+			}
+
+			continue
+		}
+
+		if !strings.HasPrefix(trimmed, "version") || specifiers == nil {
+			continue
+		}
+
+		version := strings.TrimPrefix(trimmed, "version")
+		version = strings.Trim(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(version), ":")), `"`)
+		for _, s := range specifiers {
+			entries[s] = version
+		}
+
+		specifiers = nil
+	}
+
+	return entries
+}
+
+// normaliseSpecifier removes the npm: protocol that yarn 2 and above adds to registry ranges
+func normaliseSpecifier(specifier string) string {
+	i := strings.LastIndex(specifier, "@")
+	if i < 1 {
+		return specifier
+	}
+
+	return specifier[:i+1] + strings.TrimPrefix(specifier[i+1:], "npm:")
+}
+
+// lockVersions replaces the range of each assembled dependency with the highest version locked by any
+// project that satisfies every range declared for it, so that the shared install matches what the projects
+// were tested with
+func lockVersions(t *TriforcePackageJSON, projects []*Project, declarations map[string][]Declaration) error {
+	locks := make(map[string]map[string]string)
+	for _, p := range projects {
+		locked, err := readLockedVersions(p)
+		if err != nil {
+			return err
+		}
+
+		locks[p.Name] = locked
+	}
+
+	for _, dep := range sortedDependencyNames(declarations) {
+		assembled, depType := t.Dependencies, "dependency"
+		if _, ok := assembled[dep]; !ok {
+			assembled, depType = t.DevDependencies, "devDependency"
+		}
+
+		if _, ok := assembled[dep]; !ok {
+			continue
+		}
+
+		ranges, ignored := comparableDeclarations(declarations[dep])
+		for _, d := range ignored {
+			color.Yellow(ignoredRange(depType, dep, d.Version, d.Project))
+		}
+
+		version, lockedIn, found := lockedVersion(dep, locks, ranges)
+		if !found {
+			if len(lockedIn) > 0 {
+				color.Yellow(unlocked(depType, dep, assembled[dep]))
+			}
+
+			continue
+		}
+
+		if assembled[dep] != version {
+			assembled[dep] = version
+			fmt.Println(locked(depType, dep, version, lockedIn))
+		}
+	}
+
+	return nil
+}
+
+// lockedVersion returns the highest locked version of a dependency that satisfies every declared range and
+// the projects that locked it, or every project that locked any version if none of them are satisfying
+func lockedVersion(dep string, locks map[string]map[string]string, declarations []Declaration) (string, []string, bool) {
+	lockedIn := make(map[string][]string)
+	for _, project := range sortedLockNames(locks) {
+		if version, ok := locks[project][dep]; ok {
+			lockedIn[version] = append(lockedIn[version], project)
+		}
+	}
+
+	var best *Version
+	var bestVersion string
+	var all []string
+
+	for version, projects := range lockedIn {
+		all = append(all, projects...)
+
+		v, err := parseVersion(version)
+		if err != nil || !satisfiesAll(version, declarations) {
+			continue
+		}
+
+		if best == nil || v.Compare(*best) > 0 {
+			best, bestVersion = &v, version
+		}
+	}
+
+	sort.Strings(all)
+	if best == nil {
+		return "", all, false
+	}
+
+	return bestVersion, lockedIn[bestVersion], true
+}
+
+// comparableDeclarations separates the declarations with semver ranges from those that cannot be compared
+// with a locked version, such as dist-tags and URLs, so that one of them does not stop a dependency from
+// being locked for every other project
+func comparableDeclarations(declarations []Declaration) ([]Declaration, []Declaration) {
+	var ranges, ignored []Declaration
+	for _, d := range declarations {
+		if _, err := parseRange(d.Version); err != nil {
+			ignored = append(ignored, d)
+			continue
+		}
+
+		ranges = append(ranges, d)
+	}
+
+	return ranges, ignored
+}
+
+func satisfiesAll(version string, declarations []Declaration) bool {
+	for _, d := range declarations {
+		if ok, err := satisfies(version, d.Version); err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortedLockNames(locks map[string]map[string]string) []string {
+	var names []string
+	for name := range locks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+)
+
+type InstalledPackage struct {
+	Name    string
+	Version string
+	Path    string
+	Linked  bool
+}
+
+// readInstalledPackages reads the top level packages in a node_modules folder, including scoped packages,
+// marking symlinked packages such as the projects linked by triforce as linked
+func readInstalledPackages(nodeModules string) (map[string]*InstalledPackage, error) {
+	installed := make(map[string]*InstalledPackage)
+
+	entries, err := ioutil.ReadDir(nodeModules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), "@") && entry.IsDir() {
+			scoped, err := ioutil.ReadDir(filepath.Join(nodeModules, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, s := range scoped {
+				name := entry.Name() + "/" + s.Name()
+				installed[name] = readInstalledPackage(nodeModules, name, s)
+			}
+
+			continue
+		}
+
+		installed[entry.Name()] = readInstalledPackage(nodeModules, entry.Name(), entry)
+	}
+
+	return installed, nil
+}
+
+func readInstalledPackage(nodeModules, name string, info os.FileInfo) *InstalledPackage {
+	pkg := &InstalledPackage{
+		Name:   name,
+		Path:   filepath.Join(nodeModules, filepath.FromSlash(name)),
+		Linked: info.Mode()&os.ModeSymlink != 0,
+	}
+
+	if parsed, err := gabs.ParseJSONFile(filepath.Join(pkg.Path, PackageJSON)); err == nil {
+		pkg.Version, _ = parsed.Path("version").Data().(string)
+	}
+
+	return pkg
+}
+
+func sortedPackageNames(installed map[string]*InstalledPackage) []string {
+	var names []string
+	for name := range installed {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// requiredPackages walks the installed dependency tree from the given dependencies the same way node
+// resolves them, returning the paths of every package directory that is reached
+func requiredPackages(root string, dependencies []string) map[string]bool {
+	reached := make(map[string]bool)
+
+	type request struct {
+		from string
+		name string
+	}
+
+	var queue []request
+	for _, dep := range dependencies {
+		queue = append(queue, request{from: root, name: dep})
+	}
+
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+
+		dir, ok := resolvePackage(root, r.from, r.name)
+		if !ok || reached[dir] {
+			continue
+		}
+
+		reached[dir] = true
+
+		// linked projects bring their own dependencies through the assembled package.json file
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		parsed, err := gabs.ParseJSONFile(filepath.Join(dir, PackageJSON))
+		if err != nil {
+			continue
+		}
+
+		// peer dependencies that are not installed are skipped when they cannot be resolved
+		for _, field := range []string{"dependencies", "optionalDependencies", "peerDependencies"} {
+			if data, ok := parsed.Path(field).Data().(map[string]interface{}); ok {
+				for dep := range data {
+					queue = append(queue, request{from: dir, name: dep})
+				}
+			}
+		}
+	}
+
+	return reached
+}
+
+// resolvePackage looks for a package in the node_modules folder of the given directory and every parent
+// directory up to the root, skipping directories that are node_modules folders themselves
+func resolvePackage(root, from, name string) (string, bool) {
+	for dir := from; ; dir = filepath.Dir(dir) {
+		if filepath.Base(dir) != NodeModules && !strings.HasPrefix(filepath.Base(dir), "@") {
+			candidate := filepath.Join(dir, NodeModules, filepath.FromSlash(name))
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, true
+			}
+		}
+
+		if dir == root || dir == filepath.Dir(dir) {
+			return "", false
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func Order() cli.Command {
+	return cli.Command{
+		Name:      "order",
+		ShortName: "o",
+		Usage:     "prints local projects in dependency order, grouping projects that can be processed in parallel",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.BoolFlag{Name: "flat", Usage: "print one project per line instead of grouping projects by level"},
+			cli.BoolFlag{Name: "fail-on-cycle", Usage: "exit with an error if there are cycles between local projects"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce order requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			g, err := loadProjectGraph(root, c.StringSlice("filter"), "", c.StringSlice("exclude"), c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			levels, cycles := g.Order()
+			for _, cycle := range cycles {
+				color.New(color.FgRed).Fprintf(c.App.Writer, "cycle detected between local projects: %s\n", strings.Join(cycle, " -> "))
+			}
+
+			if len(cycles) > 0 && c.Bool("fail-on-cycle") {
+				return fmt.Errorf("found %d cycle(s) between local projects", len(cycles))
+			}
+
+			for i, level := range levels {
+				if c.Bool("flat") {
+					for _, name := range level {
+						fmt.Fprintln(c.App.Writer, name)
+					}
+					continue
+				}
+
+				fmt.Fprintf(c.App.Writer, "level %d: %s\n", i+1, strings.Join(level, ", "))
+			}
+
+			return nil
+		},
+	}
+}
+
+// Order groups projects into levels where every project only depends on projects in earlier levels.
+// Projects that are part of, or depend on, a cycle are placed together in a final level and the cycles
+// are returned alongside the levels
+func (g *ProjectGraph) Order() ([][]string, [][]string) {
+	var levels [][]string
+	placed := make(map[string]bool)
+
+	for len(placed) < len(g.Projects) {
+		var level []string
+		for _, name := range g.Names() {
+			if placed[name] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range g.Dependencies[name] {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				level = append(level, name)
+			}
+		}
+
+		if len(level) == 0 {
+			break
+		}
+
+		for _, name := range level {
+			placed[name] = true
+		}
+
+		levels = append(levels, level)
+	}
+
+	if len(placed) == len(g.Projects) {
+		return levels, nil
+	}
+
+	var remaining []string
+	for _, name := range g.Names() {
+		if !placed[name] {
+			remaining = append(remaining, name)
+		}
+	}
+
+	return append(levels, remaining), g.Cycles()
+}
+
+// Cycles returns every cycle found by a depth first search, each as a path that starts and ends
+// with the same project
+func (g *ProjectGraph) Cycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var cycles [][]string
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, dep := range g.Dependencies[name] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				for i := range stack {
+					if stack[i] == dep {
+						cycle := append(append([]string{}, stack[i:]...), dep)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+
+	for _, name := range g.Names() {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], " ") < strings.Join(cycles[j], " ")
+	})
+
+	return cycles
+}
@@ -0,0 +1,83 @@
+package cli_test
+
+import (
+	"bytes"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Order", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	BeforeEach(func() {
+		p = make(map[string]*BasicPackageJSON)
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	Context("sanity checking", func() {
+		It("should throw an error if trying to order without enough args", func() {
+			args := []string{"triforce", "order"}
+			Expect(app.Run(args)).NotTo(Succeed())
+		})
+	})
+
+	Context("projects without cycles", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+			p["api-2"] = NewBasicPackageJSONBuilder().Dependency("lib-2", "github:someorg/lib-2").Build()
+			p["lib-1"] = NewBasicPackageJSONBuilder().Dependency("lib-2", "github:someorg/lib-2").Build()
+			p["lib-2"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "1.0.0").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should group projects that can be processed in parallel into levels", func() {
+			args := []string{"triforce", "order", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(Equal("level 1: lib-2\nlevel 2: api-2, lib-1\nlevel 3: api-1\n"))
+		})
+
+		It("should print one project per line in dependency order", func() {
+			args := []string{"triforce", "order", "--flat", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(Equal("lib-2\napi-2\nlib-1\napi-1\n"))
+		})
+	})
+
+	Context("projects with cycles", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+			p["lib-1"] = NewBasicPackageJSONBuilder().Dependency("lib-2", "github:someorg/lib-2").Build()
+			p["lib-2"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+			p["lib-3"] = NewBasicPackageJSONBuilder().Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should report the full cycle path and order the remaining projects last", func() {
+			args := []string{"triforce", "order", t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("cycle detected between local projects: lib-1 -> lib-2 -> lib-1"))
+			Expect(out.String()).To(ContainSubstring("level 1: lib-3\nlevel 2: api-1, lib-1, lib-2\n"))
+		})
+
+		It("should throw an error when failing on cycles", func() {
+			args := []string{"triforce", "order", "--fail-on-cycle", t.RootFolder}
+			Expect(app.Run(args)).NotTo(Succeed())
+		})
+	})
+})
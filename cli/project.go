@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+)
+
+type Project struct {
+	Name   string
+	Path   string
+	Parsed *gabs.Container
+}
+
+func loadProjects(root string, projectDirectories []string) ([]*Project, error) {
+	var projects []*Project
+
+	for _, projectDirectory := range projectDirectories {
+		pkgPath := filepath.Join(root, projectDirectory, PackageJSON)
+		if _, err := os.Stat(pkgPath); err == nil {
+			parsed, err := gabs.ParseJSONFile(pkgPath)
+			if err != nil {
+				return nil, err
+			}
+
+			projects = append(projects, &Project{
+				Name:   filepath.Base(projectDirectory),
+				Path:   filepath.Join(root, projectDirectory),
+				Parsed: parsed,
+			})
+		}
+	}
+
+	return projects, nil
+}
+
+// PackageName returns the name declared in the package.json file, falling back to the folder name
+func (p *Project) PackageName() string {
+	if name, ok := p.Parsed.Path("name").Data().(string); ok && name != "" {
+		return name
+	}
+
+	return p.Name
+}
+
+// Dependencies returns the string versions declared under the given package.json field, such as
+// "dependencies" or "devDependencies"
+func (p *Project) Dependencies(field string) map[string]string {
+	return p.stringMap(field)
+}
+
+func (p *Project) Scripts() map[string]string {
+	return p.stringMap("scripts")
+}
+
+func (p *Project) stringMap(field string) map[string]string {
+	values := make(map[string]string)
+	if data, ok := p.Parsed.Path(field).Data().(map[string]interface{}); ok {
+		for key, value := range data {
+			if v, ok := value.(string); ok {
+				values[key] = v
+			}
+		}
+	}
+
+	return values
+}
+
+// Tags returns the tags listed under "triforce.tags" in the package.json file
+func (p *Project) Tags() []string {
+	var tags []string
+	if data, ok := p.Parsed.Path("triforce.tags").Data().([]interface{}); ok {
+		for _, tag := range data {
+			if t, ok := tag.(string); ok {
+				tags = append(tags, t)
+			}
+		}
+	}
+
+	return tags
+}
+
+func projectNames(projects []*Project) []string {
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// repositoryName extracts the repository name from a private dependency version such as
+// "github:acme/lib-1#v2.3.0" or "git+ssh://git@github.com:acme/lib-1.git#develop"
+func repositoryName(version string) string {
+	if i := strings.Index(version, "#"); i > -1 {
+		version = version[:i]
+	}
+
+	version = strings.TrimSuffix(strings.TrimSuffix(version, "/"), ".git")
+	if i := strings.LastIndexAny(version, "/:"); i > -1 {
+		version = version[i+1:]
+	}
+
+	return version
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func Prune() cli.Command {
+	return cli.Command{
+		Name:  "prune",
+		Usage: "removes dangling links to local projects and lists the packages in node_modules that nothing requires",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "manifest, m", Usage: "assembled package.json file that was installed (defaults to the package.json file at the root)"},
+			cli.BoolFlag{Name: "delete, d", Usage: "also delete the packages that are not required by the assembled package.json file or any linked project"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce prune requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			manifestPath := c.String("manifest")
+			if manifestPath == "" {
+				manifestPath = filepath.Join(root, PackageJSON)
+			}
+
+			manifest, err := gabs.ParseJSONFile(manifestPath)
+			if err != nil {
+				return err
+			}
+
+			nodeModules := filepath.Join(root, NodeModules)
+			if _, err := os.Stat(nodeModules); err != nil {
+				return fmt.Errorf("no node_modules folder found at %s", root)
+			}
+
+			installed, err := readInstalledPackages(nodeModules)
+			if err != nil {
+				return err
+			}
+
+			dangling := 0
+			var required []string
+			for _, field := range []string{"dependencies", "devDependencies", "optionalDependencies"} {
+				required = append(required, dependencyNames(manifest, field)...)
+			}
+
+			for _, name := range sortedPackageNames(installed) {
+				pkg := installed[name]
+				if !pkg.Linked {
+					continue
+				}
+
+				target, err := os.Readlink(pkg.Path)
+				if err != nil {
+					return err
+				}
+
+				if _, err := os.Stat(pkg.Path); err == nil {
+					// the dependencies of linked projects are installed at the root as well
+					if parsed, err := gabs.ParseJSONFile(filepath.Join(pkg.Path, PackageJSON)); err == nil {
+						for _, field := range []string{"dependencies", "devDependencies", "optionalDependencies"} {
+							required = append(required, dependencyNames(parsed, field)...)
+						}
+					}
+
+					continue
+				}
+
+				// dangling links that point outside of the root were not made by triforce link
+				if !isProjectLinkTarget(root, pkg.Path, target) {
+					continue
+				}
+
+				if err := removePackage(nodeModules, pkg); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(c.App.Writer, "removed dangling link ./%s/%s -> %s\n", NodeModules, name, target)
+				dangling++
+			}
+
+			reached := requiredPackages(root, required)
+			orphaned := 0
+			var size int64
+
+			for _, name := range sortedPackageNames(installed) {
+				pkg := installed[name]
+				if pkg.Linked || reached[pkg.Path] {
+					continue
+				}
+
+				pkgSize, err := directorySize(pkg.Path)
+				if err != nil {
+					return err
+				}
+
+				orphaned++
+				size += pkgSize
+
+				if !c.Bool("delete") {
+					color.New(color.FgYellow).Fprintf(c.App.Writer, "orphaned package \"%s\" (installed version \"%s\", %s)\n", name, pkg.Version, humanBytes(pkgSize))
+					continue
+				}
+
+				if err := removePackage(nodeModules, pkg); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(c.App.Writer, "removed orphaned package \"%s\" (installed version \"%s\", %s)\n", name, pkg.Version, humanBytes(pkgSize))
+			}
+
+			if c.Bool("delete") {
+				color.New(color.FgGreen).Fprintf(c.App.Writer, "\nremoved %d dangling link(s) and %d orphaned package(s), reclaiming %s\n", dangling, orphaned, humanBytes(size))
+				return nil
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\nremoved %d dangling link(s) and found %d orphaned package(s) taking up %s\n", dangling, orphaned, humanBytes(size))
+			if orphaned > 0 {
+				fmt.Fprintln(c.App.Writer, "run triforce prune with --delete to remove them")
+			}
+
+			return nil
+		},
+	}
+}
+
+func dependencyNames(parsed *gabs.Container, field string) []string {
+	var names []string
+	if data, ok := parsed.Path(field).Data().(map[string]interface{}); ok {
+		for name := range data {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// removePackage removes a top level package or link from a node_modules folder, along with its scope
+// folder if it was the last package in it
+func removePackage(nodeModules string, pkg *InstalledPackage) error {
+	if err := os.RemoveAll(pkg.Path); err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(pkg.Name, "@") {
+		return nil
+	}
+
+	scope := filepath.Dir(pkg.Path)
+	if entries, err := ioutil.ReadDir(scope); err == nil && len(entries) == 0 {
+		return os.Remove(scope)
+	}
+
+	return nil
+}
+
+// directorySize adds up the size of every file in a folder without following symlinks
+func directorySize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,107 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prune", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	exists := func(name string) bool {
+		_, err := os.Lstat(filepath.Join(t.RootFolder, "node_modules", filepath.FromSlash(name)))
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["lib-1"] = NewBasicPackageJSONBuilder().DevDependency("dep-d", "^1.0.0").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.0.0").Build())).To(Succeed())
+		Expect(t.Install("dep-a", "1.0.0", "dep-c")).To(Succeed())
+		Expect(t.Install("dep-c", "1.0.0")).To(Succeed())
+		Expect(t.Install("dep-d", "1.0.0")).To(Succeed())
+		Expect(t.Install("dep-e", "1.0.0")).To(Succeed())
+		Expect(t.Install("@scope/dep-f", "1.0.0")).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "node_modules", "dep-e", "index.js"), make([]byte, 2048), os.FileMode(0666))).To(Succeed())
+
+		Expect(os.Symlink("../lib-1", filepath.Join(t.RootFolder, "node_modules", "lib-1"))).To(Succeed())
+		Expect(os.Symlink("../lib-2", filepath.Join(t.RootFolder, "node_modules", "lib-2"))).To(Succeed())
+		Expect(os.Symlink("/nonexistent/ext", filepath.Join(t.RootFolder, "node_modules", "ext"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should remove dangling links to the root and list orphaned packages", func() {
+		args := []string{"triforce", "prune", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring("removed dangling link ./node_modules/lib-2 -> ../lib-2"))
+		Expect(out.String()).To(ContainSubstring("orphaned package \"@scope/dep-f\" (installed version \"1.0.0\""))
+		Expect(out.String()).To(ContainSubstring("orphaned package \"dep-e\" (installed version \"1.0.0\""))
+		Expect(out.String()).NotTo(ContainSubstring("\"dep-a\""))
+		Expect(out.String()).NotTo(ContainSubstring("\"dep-c\""))
+		Expect(out.String()).NotTo(ContainSubstring("\"dep-d\""))
+		Expect(out.String()).To(ContainSubstring("removed 1 dangling link(s) and found 2 orphaned package(s) taking up"))
+		Expect(out.String()).To(ContainSubstring("run triforce prune with --delete to remove them"))
+
+		Expect(exists("lib-2")).To(BeFalse())
+		Expect(exists("ext")).To(BeTrue())
+		Expect(exists("lib-1")).To(BeTrue())
+		Expect(exists("dep-e")).To(BeTrue())
+	})
+
+	It("should keep installed peer dependencies of required packages", func() {
+		manifest := filepath.Join(t.RootFolder, "node_modules", "dep-c", "package.json")
+		bytes, err := ioutil.ReadFile(manifest)
+		Expect(err).NotTo(HaveOccurred())
+
+		pkg := make(map[string]interface{})
+		Expect(json.Unmarshal(bytes, &pkg)).To(Succeed())
+		pkg["peerDependencies"] = map[string]string{"dep-e": "^1.0.0", "dep-g": "^1.0.0"}
+
+		bytes, err = json.Marshal(pkg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(manifest, bytes, os.FileMode(0666))).To(Succeed())
+
+		args := []string{"triforce", "prune", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).NotTo(ContainSubstring("\"dep-e\""))
+		Expect(out.String()).NotTo(ContainSubstring("\"dep-g\""))
+		Expect(out.String()).To(ContainSubstring("found 1 orphaned package(s)"))
+	})
+
+	It("should delete orphaned packages and report the space reclaimed", func() {
+		args := []string{"triforce", "prune", "--delete", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring("removed orphaned package \"dep-e\""))
+		Expect(out.String()).To(MatchRegexp(`removed 1 dangling link\(s\) and 2 orphaned package\(s\), reclaiming \d+\.\d KiB`))
+
+		Expect(exists("dep-e")).To(BeFalse())
+		Expect(exists("@scope")).To(BeFalse())
+		for _, name := range []string{"dep-a", "dep-c", "dep-d", "lib-1"} {
+			Expect(exists(name)).To(BeTrue())
+		}
+	})
+})
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+const (
+	refMatches   = "matches"
+	refDiffers   = "differs"
+	refUnknown   = "unknown ref"
+	refNotGitDir = "not a git checkout"
+)
+
+// GitCheckout describes the state of the git checkout of a local project
+type GitCheckout struct {
+	Commit string
+	Branch string
+	Tags   []string
+}
+
+func (c *GitCheckout) String() string {
+	description := c.Commit
+	if len(description) > 7 {
+		description = description[:7]
+	}
+
+	if c.Branch != "" {
+		description = fmt.Sprintf("%s@%s", c.Branch, description)
+	}
+
+	if len(c.Tags) > 0 {
+		description = fmt.Sprintf("%s (%s)", description, strings.Join(c.Tags, ", "))
+	}
+
+	return description
+}
+
+// RefCheck compares the ref a project expects for a private dependency with the local checkout it is linked to
+type RefCheck struct {
+	Project    string
+	Dependency string
+	Expected   string
+	Checkout   string
+	Status     string
+}
+
+func Refs() cli.Command {
+	return cli.Command{
+		Name:  "refs",
+		Usage: "compares the git refs in the versions of private dependencies with the local checkouts they are linked to",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.BoolFlag{Name: "all, a", Usage: "also list the private dependencies whose local checkout matches the expected ref"},
+			cli.BoolFlag{Name: "strict", Usage: "fail if any private dependency expects a different ref than its local checkout"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce refs requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			folders, err := getProjectFolders(root, []string{})
+			if err != nil {
+				return err
+			}
+
+			all, err := loadProjects(root, folders)
+			if err != nil {
+				return err
+			}
+
+			selectedFolders, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			selected, err := loadProjects(root, selectedFolders)
+			if err != nil {
+				return err
+			}
+
+			checks := checkRefs(newProjectGraph(all, exclude), selected, exclude)
+
+			diverged := 0
+			tw := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "PROJECT\tDEPENDENCY\tEXPECTED\tCHECKOUT\tSTATUS")
+			for _, check := range checks {
+				if check.Status != refMatches {
+					diverged++
+				} else if !c.Bool("all") {
+					continue
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", check.Project, check.Dependency, check.Expected, check.Checkout, check.Status)
+			}
+
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			if diverged > 0 && c.Bool("strict") {
+				return fmt.Errorf("%d private dependencies expect a different ref than their local checkout", diverged)
+			}
+
+			if diverged > 0 {
+				color.New(color.FgYellow).Fprintf(c.App.Writer, "\n%d private dependencies expect a different ref than their local checkout\n", diverged)
+				return nil
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\nall %d private dependencies with a ref match their local checkout\n", len(checks))
+			return nil
+		},
+	}
+}
+
+// checkRefs compares every private dependency of the selected projects that has a ref and is linked to a
+// local project with the git checkout of that project
+func checkRefs(g *ProjectGraph, projects []*Project, exclude []string) []RefCheck {
+	packageNames := make(map[string]string)
+	for _, p := range g.Projects {
+		packageNames[p.PackageName()] = p.Name
+	}
+
+	checkouts := make(map[string]*GitCheckout)
+	var checks []RefCheck
+
+	for _, p := range projects {
+		for _, field := range []string{"dependencies", "devDependencies"} {
+			for dep, version := range p.Dependencies(field) {
+				i := strings.Index(version, "#")
+				if i < 0 || !isAPrivateDependency(version, exclude...) {
+					continue
+				}
+
+				local, ok := g.resolve(dep, version, packageNames, exclude)
+				if !ok {
+					continue
+				}
+
+				check := RefCheck{Project: p.Name, Dependency: dep, Expected: version[i+1:]}
+				dir := g.Projects[local].Path
+
+				if !isGitCheckout(dir) {
+					check.Status = refNotGitDir
+					checks = append(checks, check)
+					continue
+				}
+
+				if _, ok := checkouts[local]; !ok {
+					checkouts[local] = inspectCheckout(dir)
+				}
+
+				check.Checkout = checkouts[local].String()
+				check.Status = compareRef(dir, checkouts[local], check.Expected)
+				checks = append(checks, check)
+			}
+		}
+	}
+
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Project != checks[j].Project {
+			return checks[i].Project < checks[j].Project
+		}
+
+		return checks[i].Dependency < checks[j].Dependency
+	})
+
+	return checks
+}
+
+func inspectCheckout(dir string) *GitCheckout {
+	checkout := &GitCheckout{}
+	checkout.Commit, _ = git(dir, "rev-parse", "HEAD")
+
+	if branch, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		checkout.Branch = branch
+	}
+
+	if tags, err := git(dir, "tag", "--points-at", "HEAD"); err == nil && tags != "" {
+		checkout.Tags = strings.Split(tags, "\n")
+	}
+
+	return checkout
+}
+
+// compareRef checks if a ref, which npm allows to be a branch, tag, commit or semver range of tags, matches
+// the commit that is checked out
+func compareRef(dir string, checkout *GitCheckout, ref string) string {
+	if strings.HasPrefix(ref, "semver:") {
+		for _, tag := range checkout.Tags {
+			if ok, err := satisfies(tag, strings.TrimPrefix(ref, "semver:")); err == nil && ok {
+				return refMatches
+			}
+		}
+
+		return refDiffers
+	}
+
+	if ref == checkout.Branch || contains(checkout.Tags, ref) {
+		return refMatches
+	}
+
+	commit, err := git(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return refUnknown
+	}
+
+	if commit == checkout.Commit {
+		return refMatches
+	}
+
+	return refDiffers
+}
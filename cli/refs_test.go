@@ -0,0 +1,100 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Refs", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	// tagLibrary commits lib-1 as v1.0.0 and v1.1.0 and leaves v1.1.0 checked out on a feature branch
+	tagLibrary := func() {
+		dir := filepath.Join(t.RootFolder, "lib-1")
+		Expect(gitCommitAll(dir)).To(Succeed())
+		Expect(gitRun(dir, "tag", "v1.0.0")).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "CHANGELOG.md"), []byte("1.1.0\n"), os.FileMode(0666))).To(Succeed())
+		Expect(gitRun(dir, "add", "--all")).To(Succeed())
+		Expect(gitRun(dir, "commit", "--quiet", "--message", "release 1.1.0")).To(Succeed())
+		Expect(gitRun(dir, "tag", "v1.1.0")).To(Succeed())
+		Expect(gitRun(dir, "checkout", "--quiet", "-b", "feature")).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should pass when every ref matches the local checkout", func() {
+		p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#v1.1.0").Build()
+		p["api-2"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#feature").Build()
+		p["api-3"] = NewBasicPackageJSONBuilder().DevDependency("lib-1", "github:someorg/lib-1#semver:^1.1.0").Build()
+		p["api-4"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+		tagLibrary()
+
+		args := []string{"triforce", "refs", "--all", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).To(MatchRegexp(`api-1\s+lib-1\s+v1\.1\.0\s+feature@[0-9a-f]{7} \(v1\.1\.0\)\s+matches`))
+		Expect(out.String()).To(MatchRegexp(`api-2\s+lib-1\s+feature\s+.*matches`))
+		Expect(out.String()).To(MatchRegexp(`api-3\s+lib-1\s+semver:\^1\.1\.0\s+.*matches`))
+		Expect(out.String()).NotTo(ContainSubstring("api-4"))
+		Expect(out.String()).To(ContainSubstring("all 3 private dependencies with a ref match their local checkout"))
+	})
+
+	It("should report refs that differ from or are unknown to the local checkout", func() {
+		p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#v1.0.0").Build()
+		p["api-2"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#semver:^2.0.0").Build()
+		p["api-3"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#missing-branch").Build()
+		p["api-4"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#v1.1.0").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+		tagLibrary()
+
+		args := []string{"triforce", "refs", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("3 private dependencies expect a different ref than their local checkout"))
+
+		By("throwing an error in strict mode", func() {
+			args := []string{"triforce", "refs", "--strict", t.RootFolder}
+			Expect(app.Run(args)).To(MatchError("3 private dependencies expect a different ref than their local checkout"))
+		})
+
+		Expect(out.String()).To(MatchRegexp(`api-1\s+lib-1\s+v1\.0\.0\s+feature@[0-9a-f]{7} \(v1\.1\.0\)\s+differs`))
+		Expect(out.String()).To(MatchRegexp(`api-2\s+lib-1\s+semver:\^2\.0\.0\s+.*differs`))
+		Expect(out.String()).To(MatchRegexp(`api-3\s+lib-1\s+missing-branch\s+.*unknown ref`))
+		Expect(out.String()).NotTo(ContainSubstring("api-4"))
+	})
+
+	It("should report local projects that are not git checkouts", func() {
+		p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("lib-1", "github:someorg/lib-1#v1.0.0").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		args := []string{"triforce", "refs", "--strict", "--filter", "api-1", t.RootFolder}
+		Expect(app.Run(args)).To(MatchError("1 private dependencies expect a different ref than their local checkout"))
+		Expect(out.String()).To(MatchRegexp(`api-1\s+lib-1\s+v1\.0\.0\s+not a git checkout`))
+	})
+})
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func Run() cli.Command {
+	return cli.Command{
+		Name:      "run",
+		ShortName: "r",
+		Usage:     "runs an npm script in every project that defines it in dependency order",
+		UsageText: "triforce run [command options] root script [-- arguments...]",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "since, s", Usage: "only include projects affected by changes since this git ref"},
+			cli.IntFlag{Name: "concurrency, c", Usage: "maximum number of projects to run the script in at the same time", Value: 1},
+			cli.BoolFlag{Name: "keep-going, k", Usage: "keep running the script in projects that do not depend on a failed project"},
+			cli.StringFlag{Name: "npm-client", Usage: "package manager used to run the script", Value: "npm"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 2 {
+				return fmt.Errorf("triforce run requires a root meta or monorepo folder and a script as arguments")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			script := c.Args().Get(1)
+			extra := c.Args()[2:]
+			if len(extra) > 0 && extra[0] == "--" {
+				extra = extra[1:]
+			}
+
+			g, err := loadProjectGraph(root, c.StringSlice("filter"), c.String("since"), c.StringSlice("exclude"), c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			command := []string{c.String("npm-client"), "run", script}
+			if len(extra) > 0 {
+				command = append(append(command, "--"), extra...)
+			}
+
+			commands := make(map[string][]string)
+			for _, name := range g.Names() {
+				if _, ok := g.Projects[name].Scripts()[script]; !ok {
+					color.New(color.FgYellow).Fprintf(c.App.Writer, "skipping %s (no \"%s\" script defined)\n", name, script)
+					continue
+				}
+
+				commands[name] = command
+			}
+
+			return runAndSummarise(c, g, commands)
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+	var npmClient string
+
+	BeforeEach(func() {
+		p = make(map[string]*BasicPackageJSON)
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	Context("sanity checking", func() {
+		It("should throw an error if trying to run without a script", func() {
+			args := []string{"triforce", "run", "."}
+			Expect(app.Run(args)).NotTo(Succeed())
+		})
+	})
+
+	Context("projects with and without the script defined", func() {
+		BeforeEach(func() {
+			p["api-1"] = NewBasicPackageJSONBuilder().
+				Dependency("lib-1", "github:someorg/lib-1").
+				Script("build", "tsc").
+				Build()
+
+			p["lib-1"] = NewBasicPackageJSONBuilder().Script("build", "tsc").Build()
+			p["lib-2"] = NewBasicPackageJSONBuilder().Script("test", "mocha").Build()
+
+			t, err = NewTestSpace(p)
+			Expect(err).NotTo(HaveOccurred())
+
+			// a stand-in for npm that records the project and arguments it was run with
+			npmClient, err = filepath.Abs(filepath.Join(t.RootFolder, "fake-npm"))
+			Expect(err).NotTo(HaveOccurred())
+			script := []byte("#!/bin/sh\necho \"$(basename $PWD) $@\" >> ../runs.txt\n")
+			Expect(ioutil.WriteFile(npmClient, script, os.FileMode(0755))).To(Succeed())
+		})
+
+		It("should only run the script in projects that define it, in dependency order", func() {
+			args := []string{"triforce", "run", "--npm-client", npmClient, t.RootFolder, "build"}
+			Expect(app.Run(args)).To(Succeed())
+
+			runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runs)).To(Equal("lib-1 run build\napi-1 run build\n"))
+
+			Expect(out.String()).To(ContainSubstring(`skipping lib-2 (no "build" script defined)`))
+			Expect(out.String()).To(MatchRegexp(`lib-2\s+skipped\s+-\s+-`))
+		})
+
+		It("should pass extra arguments to the script", func() {
+			args := []string{"triforce", "run", "--npm-client", npmClient, t.RootFolder, "test", "--", "--grep", "unit"}
+			Expect(app.Run(args)).To(Succeed())
+
+			runs, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "runs.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runs)).To(Equal("lib-2 run test -- --grep unit\n"))
+		})
+	})
+})
@@ -0,0 +1,482 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3PartSize is the size of the parts that larger files are uploaded in, which S3 requires to be at least 5MiB
+const s3PartSize = 8 << 20
+
+var errUploadNotFound = errors.New("upload not found")
+
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Backend stores snapshots in a bucket of any S3 compatible API using path style requests signed with
+// AWS signature version 4
+type S3Backend struct {
+	Bucket  string
+	Prefix  string
+	Config  S3Config
+	Client  *http.Client
+	Uploads string
+}
+
+// s3Upload records a multipart upload in progress so that a later attempt to upload the same file can resume it
+type s3Upload struct {
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+	UploadID string `json:"uploadId"`
+}
+
+type s3Part struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+func newS3Backend(bucket, prefix string, config S3Config) (*S3Backend, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 snapshot storage requires a bucket")
+	}
+
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 snapshot storage requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY to be set")
+	}
+
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	// uploads are recorded in a fixed place so that an interrupted upload can be resumed
+	uploads := filepath.Join(os.TempDir(), "triforce-uploads")
+	return &S3Backend{Bucket: bucket, Prefix: prefix, Config: config, Client: http.DefaultClient, Uploads: uploads}, nil
+}
+
+func (b *S3Backend) String() string {
+	return fmt.Sprintf("s3://%s/%s", b.Bucket, b.Prefix)
+}
+
+func (b *S3Backend) objectURL(name string) string {
+	key := name
+	if b.Prefix != "" {
+		key = b.Prefix + "/" + name
+	}
+
+	return fmt.Sprintf("%s/%s/%s", b.Config.Endpoint, b.Bucket, key)
+}
+
+// Put uploads files larger than a single part in parts, so that an interrupted upload can be resumed
+func (b *S3Backend) Put(name, file string) error {
+	checksum, err := fileChecksum(file)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > s3PartSize {
+		return b.putMultipart(name, f, info.Size(), checksum)
+	}
+
+	req, err := b.request(http.MethodPut, name, nil, f)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = info.Size()
+	res, _, err := b.send(req, checksum)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("could not upload %s to %s: %s", name, b, res.Status)
+	}
+
+	return nil
+}
+
+func (b *S3Backend) Open(name string, offset int64) (io.ReadCloser, bool, error) {
+	req, err := b.request(http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	b.sign(req, emptyPayloadHash)
+	body, resumed, err := openRange(b.Client, req, offset)
+	if status, ok := err.(*statusError); ok && status.Code == http.StatusForbidden {
+		// S3 answers 403 instead of 404 for missing keys if the credentials are not allowed to list the bucket
+		return nil, false, errSnapshotNotFound
+	}
+
+	return body, resumed, err
+}
+
+// putMultipart uploads a file in parts, skipping the parts that were already uploaded by an earlier attempt
+// to upload the same file under the same name
+func (b *S3Backend) putMultipart(name string, f *os.File, size int64, checksum string) error {
+	state := filepath.Join(b.Uploads, sha256Hex([]byte(b.objectURL(name)))+".json")
+	upload, uploaded, err := b.resumeUpload(state, name, checksum)
+	if err != nil {
+		return err
+	}
+
+	var parts []s3Part
+	for number, offset := 1, int64(0); offset < size; number, offset = number+1, offset+s3PartSize {
+		length := size - offset
+		if length > s3PartSize {
+			length = s3PartSize
+		}
+
+		if part, ok := uploaded[number]; ok && part.Size == length {
+			parts = append(parts, part)
+			continue
+		}
+
+		etag, err := b.putPart(name, upload.UploadID, number, io.NewSectionReader(f, offset, length), length)
+		if err != nil {
+			return fmt.Errorf("upload of %s interrupted, run the command again to resume: %s", name, err)
+		}
+
+		parts = append(parts, s3Part{PartNumber: number, ETag: etag, Size: length})
+	}
+
+	if err := b.completeUpload(name, upload.UploadID, parts); err != nil {
+		return err
+	}
+
+	return os.Remove(state)
+}
+
+// resumeUpload returns the multipart upload recorded for a name along with the parts uploaded so far if it
+// was started for the same file, or starts a new one otherwise
+func (b *S3Backend) resumeUpload(state, name, checksum string) (*s3Upload, map[int]s3Part, error) {
+	var upload s3Upload
+	if content, err := ioutil.ReadFile(state); err == nil && json.Unmarshal(content, &upload) == nil {
+		if upload.Checksum == checksum {
+			parts, err := b.listParts(name, upload.UploadID)
+			if err == nil {
+				return &upload, parts, nil
+			}
+
+			if err != errUploadNotFound {
+				return nil, nil, err
+			}
+		} else {
+			// the parts of a different file are of no use, and aborting only frees the space they take up, so
+			// a failure to abort does not stop the new upload
+			b.abortUpload(name, upload.UploadID)
+		}
+	}
+
+	req, err := b.request(http.MethodPost, name, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, body, err := b.send(req, emptyPayloadHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+
+	if res.StatusCode/100 != 2 || xml.Unmarshal(body, &result) != nil || result.UploadID == "" {
+		return nil, nil, fmt.Errorf("could not start uploading %s to %s: %s", name, b, res.Status)
+	}
+
+	upload = s3Upload{Name: name, Checksum: checksum, UploadID: result.UploadID}
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(b.Uploads, os.FileMode(0755)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ioutil.WriteFile(state, content, os.FileMode(0644)); err != nil {
+		return nil, nil, err
+	}
+
+	return &upload, make(map[int]s3Part), nil
+}
+
+func (b *S3Backend) listParts(name, uploadID string) (map[int]s3Part, error) {
+	parts := make(map[int]s3Part)
+	marker := 0
+
+	for {
+		query := url.Values{"uploadId": {uploadID}}
+		if marker > 0 {
+			query.Set("part-number-marker", strconv.Itoa(marker))
+		}
+
+		req, err := b.request(http.MethodGet, name, query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, body, err := b.send(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode == http.StatusNotFound {
+			return nil, errUploadNotFound
+		}
+
+		var result struct {
+			Parts                []s3Part `xml:"Part"`
+			IsTruncated          bool
+			NextPartNumberMarker int
+		}
+
+		if res.StatusCode/100 != 2 || xml.Unmarshal(body, &result) != nil {
+			return nil, fmt.Errorf("could not list the uploaded parts of %s in %s: %s", name, b, res.Status)
+		}
+
+		for _, part := range result.Parts {
+			parts[part.PartNumber] = part
+		}
+
+		if !result.IsTruncated {
+			return parts, nil
+		}
+
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (b *S3Backend) putPart(name, uploadID string, number int, part *io.SectionReader, length int64) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, part); err != nil {
+		return "", err
+	}
+
+	if _, err := part.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	req, err := b.request(http.MethodPut, name, url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}, part)
+	if err != nil {
+		return "", err
+	}
+
+	req.ContentLength = length
+	res, _, err := b.send(req, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return "", err
+	}
+
+	if res.StatusCode/100 != 2 {
+		return "", fmt.Errorf("could not upload part %d: %s", number, res.Status)
+	}
+
+	return res.Header.Get("ETag"), nil
+}
+
+func (b *S3Backend) completeUpload(name, uploadID string, parts []s3Part) error {
+	type completedPart struct {
+		PartNumber int
+		ETag       string
+	}
+
+	completion := struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{}
+
+	for _, part := range parts {
+		completion.Parts = append(completion.Parts, completedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	payload, err := xml.Marshal(completion)
+	if err != nil {
+		return err
+	}
+
+	req, err := b.request(http.MethodPost, name, url.Values{"uploadId": {uploadID}}, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	res, body, err := b.send(req, sha256Hex(payload))
+	if err != nil {
+		return err
+	}
+
+	// S3 can report a failure to complete an upload in the body of a 200 response
+	if res.StatusCode/100 != 2 || bytes.Contains(body, []byte("<Error>")) {
+		return fmt.Errorf("could not complete uploading %s to %s: %s", name, b, res.Status)
+	}
+
+	return nil
+}
+
+func (b *S3Backend) abortUpload(name, uploadID string) error {
+	req, err := b.request(http.MethodDelete, name, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = b.send(req, emptyPayloadHash)
+	return err
+}
+
+func (b *S3Backend) request(method, name string, query url.Values, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, b.objectURL(name), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.URL.RawQuery = canonicalQuery(query)
+	return req, nil
+}
+
+// send signs and sends a request, reading the whole response since every response other than the contents
+// of an object is a small XML document
+func (b *S3Backend) send(req *http.Request, payloadHash string) (*http.Response, []byte, error) {
+	b.sign(req, payloadHash)
+	res, err := b.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	return res, body, err
+}
+
+// sign adds an AWS signature version 4 Authorization header to a request
+func (b *S3Backend) sign(req *http.Request, payloadHash string) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	timestamp := now.Format("20060102T150405Z")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.Config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.Config.SessionToken)
+	}
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := strings.TrimSpace(req.Header.Get(name))
+		if name == "host" {
+			value = req.URL.Host
+		}
+
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, value)
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		(&url.URL{Path: req.URL.Path}).EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, b.Config.Region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", timestamp, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+b.Config.SecretAccessKey), date)
+	for _, part := range []string{b.Config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", b.Config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes a query string the way AWS signature version 4 expects it, with sorted keys and
+// spaces encoded as %20
+func canonicalQuery(query url.Values) string {
+	var keys []string
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func s3Escape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// parseVersion parses full or partial versions such as "v1.2.3", "1.2" and "1.2.3-beta.1", treating
+// missing or wildcard minor and patch numbers as zero
+func parseVersion(version string) (Version, error) {
+	v := Version{}
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version = strings.TrimPrefix(version, "=")
+
+	if i := strings.Index(version, "+"); i > -1 {
+		version = version[:i]
+	}
+
+	if i := strings.Index(version, "-"); i > -1 {
+		v.Prerelease = version[i+1:]
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %s", version)
+	}
+
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if i > 0 && isWildcard(part) {
+			break
+		}
+
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %s", version)
+		}
+
+		*numbers[i] = n
+	}
+
+	return v, nil
+}
+
+func isWildcard(part string) bool {
+	return part == "x" || part == "X" || part == "*"
+}
+
+func (v Version) String() string {
+	if v.Prerelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", v.Major, v.Minor, v.Patch, v.Prerelease)
+	}
+
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or greater than o
+func (v Version) Compare(o Version) int {
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	case v.Prerelease < o.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+type comparator struct {
+	operator string
+	version  Version
+}
+
+func (c comparator) test(v Version) bool {
+	switch c.operator {
+	case ">=":
+		return v.Compare(c.version) >= 0
+	case ">":
+		return v.Compare(c.version) > 0
+	case "<=":
+		return v.Compare(c.version) <= 0
+	case "<":
+		return v.Compare(c.version) < 0
+	default:
+		return v.Compare(c.version) == 0
+	}
+}
+
+// satisfies reports whether a version satisfies an npm range such as "^1.2.0", ">=1.0.0 <2.0.0",
+// "1.2.x", "1.0.0 - 1.4.0" or "^1.0.0 || ^2.0.0", returning an error for versions and ranges that
+// cannot be compared, such as git URLs and dist-tags
+func satisfies(version, r string) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	sets, err := parseRange(r)
+	if err != nil {
+		return false, err
+	}
+
+	for _, set := range sets {
+		matched := true
+		for _, c := range set {
+			if !c.test(v) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func parseRange(r string) ([][]comparator, error) {
+	var sets [][]comparator
+
+	for _, set := range strings.Split(r, "||") {
+		fields := strings.Fields(set)
+
+		if len(fields) == 3 && fields[1] == "-" {
+			lower, _, err := parsePartial(fields[0])
+			if err != nil {
+				return nil, err
+			}
+
+			upper, parts, err := parsePartial(fields[2])
+			if err != nil {
+				return nil, err
+			}
+
+			comparators := []comparator{{">=", lower}}
+			if parts == 3 {
+				comparators = append(comparators, comparator{"<=", upper})
+			} else if parts > 0 {
+				comparators = append(comparators, comparator{"<", bump(upper, parts)})
+			}
+
+			sets = append(sets, comparators)
+			continue
+		}
+
+		var comparators []comparator
+		for _, field := range fields {
+			c, err := parseComparator(field)
+			if err != nil {
+				return nil, err
+			}
+
+			comparators = append(comparators, c...)
+		}
+
+		sets = append(sets, comparators)
+	}
+
+	return sets, nil
+}
+
+// parsePartial parses a version which may be missing its minor and patch numbers or use wildcards
+// for them, returning the number of parts that were given
+func parsePartial(partial string) (Version, int, error) {
+	parts := 0
+	for _, part := range strings.Split(strings.SplitN(partial, "-", 2)[0], ".") {
+		if part == "" || isWildcard(part) {
+			break
+		}
+		parts++
+	}
+
+	if parts == 0 {
+		return Version{}, 0, nil
+	}
+
+	v, err := parseVersion(partial)
+	return v, parts, err
+}
+
+// bump returns the lowest version above every version matching a partial version with the given
+// number of parts, so that "1.2" becomes "1.3.0" and "1" becomes "2.0.0"
+func bump(v Version, parts int) Version {
+	switch parts {
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
+
+func parseComparator(field string) ([]comparator, error) {
+	operator := ""
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(field, op) {
+			operator = op
+			break
+		}
+	}
+
+	v, parts, err := parsePartial(strings.TrimPrefix(field[len(operator):], "v"))
+	if err != nil {
+		return nil, err
+	}
+
+	if parts == 0 {
+		if operator == "<" || operator == ">" {
+			return []comparator{{"<", Version{}}}, nil
+		}
+
+		return nil, nil
+	}
+
+	switch operator {
+	case "^":
+		upper := Version{Major: v.Major + 1}
+		if v.Major == 0 {
+			switch {
+			case parts == 1:
+				upper = Version{Major: 1}
+			case v.Minor > 0 || parts == 2:
+				upper = Version{Minor: v.Minor + 1}
+			default:
+				upper = Version{Patch: v.Patch + 1}
+			}
+		}
+
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		if parts == 3 {
+			return []comparator{{">=", v}, {"<", bump(v, 2)}}, nil
+		}
+
+		return []comparator{{">=", v}, {"<", bump(v, parts)}}, nil
+	case ">":
+		if parts < 3 {
+			return []comparator{{">=", bump(v, parts)}}, nil
+		}
+	case "<=":
+		if parts < 3 {
+			return []comparator{{"<", bump(v, parts)}}, nil
+		}
+	case "", "=":
+		if parts < 3 {
+			return []comparator{{">=", v}, {"<", bump(v, parts)}}, nil
+		}
+
+		return []comparator{{"=", v}}, nil
+	}
+
+	return []comparator{{operator, v}}, nil
+}
+
+// rangeMajor returns the major version targeted by a simple range such as "^1.2.0", "~1.2" or "1.x",
+// and false for anything that does not target a single major version
+func rangeMajor(r string) (int, bool) {
+	v, ok := rangeVersion(r)
+	return v.Major, ok
+}
+
+// rangeVersion returns the version at the base of a simple range such as "^1.2.0", "~1.2" or "1.x", and
+// false for anything that does not target a single major version
+func rangeVersion(r string) (Version, bool) {
+	r = strings.TrimSpace(r)
+	if strings.ContainsAny(r, "|<> ") {
+		return Version{}, false
+	}
+
+	r = strings.TrimLeft(r, "^~=")
+	if r == "" || isWildcard(strings.Split(r, ".")[0]) {
+		return Version{}, false
+	}
+
+	v, err := parseVersion(r)
+	if err != nil {
+		return Version{}, false
+	}
+
+	return v, true
+}
+
+// isHigherRange reports whether a range targets a higher version than another, comparing the versions at the
+// base of simple ranges numerically so that "^1.10.0" is higher than "^1.9.0", and falling back to comparing
+// anything else, such as git URLs and dist-tags, as strings
+func isHigherRange(original, new string) bool {
+	o, ok := rangeVersion(original)
+	if !ok {
+		return shouldUpdate(original, new)
+	}
+
+	n, ok := rangeVersion(new)
+	if !ok {
+		return shouldUpdate(original, new)
+	}
+
+	return n.Compare(o) > 0
+}
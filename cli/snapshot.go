@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+const SnapshotExtension = ".tar.gz"
+
+const ChecksumExtension = ".sha256"
+
+func Snapshot() cli.Command {
+	flags := []cli.Flag{
+		cli.StringFlag{Name: "manifest, m", Usage: "assembled package.json file the snapshot is keyed by (defaults to the package.json file at the root)"},
+		cli.StringFlag{Name: "directory, d", Usage: "directory to keep snapshots in (defaults to ~/.triforce/snapshots)"},
+		cli.StringFlag{Name: "url, u", Usage: "http, https or s3 URL to keep snapshots at instead of a local directory", EnvVar: "TRIFORCE_SNAPSHOT_URL"},
+		cli.StringFlag{Name: "s3-endpoint", Usage: "endpoint of an S3 compatible API (defaults to AWS S3 in the given region)", EnvVar: "TRIFORCE_S3_ENDPOINT"},
+		cli.StringFlag{Name: "s3-region", Usage: "region of the S3 bucket", Value: "us-east-1", EnvVar: "AWS_REGION"},
+	}
+
+	return cli.Command{
+		Name:  "snapshot",
+		Usage: "saves and restores snapshots of the node_modules folder keyed by a hash of the assembled package.json file",
+		Subcommands: []cli.Command{
+			{
+				Name:  "save",
+				Usage: "saves the node_modules folder at the root as a snapshot",
+				Flags: flags,
+				Action: func(c *cli.Context) error {
+					root, key, backend, err := snapshotArgs(c, "save")
+					if err != nil {
+						return err
+					}
+
+					file, err := ioutil.TempFile("", "triforce-snapshot")
+					if err != nil {
+						return err
+					}
+					defer os.Remove(file.Name())
+
+					if err := file.Close(); err != nil {
+						return err
+					}
+
+					if err := saveSnapshot(root, file.Name()); err != nil {
+						return err
+					}
+
+					if err := uploadSnapshot(backend, key, file.Name()); err != nil {
+						return err
+					}
+
+					color.New(color.FgGreen).Fprintf(c.App.Writer, "saved snapshot %s to %s\n", key, backend)
+					return nil
+				},
+			},
+			{
+				Name:  "restore",
+				Usage: "restores the snapshot matching the assembled package.json file to the node_modules folder at the root",
+				Flags: append(append([]cli.Flag{}, flags...),
+					cli.StringFlag{Name: "store", Usage: "content-addressed store to hardlink the files of the snapshot from instead of extracting them", EnvVar: "TRIFORCE_STORE"},
+				),
+				Action: func(c *cli.Context) error {
+					root, key, backend, err := snapshotArgs(c, "restore")
+					if err != nil {
+						return err
+					}
+
+					var store *PackageStore
+					if c.String("store") != "" {
+						store = newStore(c.String("store"))
+					}
+
+					// snapshots that are already in the store do not need to be downloaded at all
+					if store == nil || !store.Has(key) {
+						// downloads are kept in a fixed place so that an interrupted download can be resumed
+						downloads := filepath.Join(os.TempDir(), "triforce-snapshots")
+						if err := os.MkdirAll(downloads, os.FileMode(0755)); err != nil {
+							return err
+						}
+
+						file := filepath.Join(downloads, key+SnapshotExtension)
+						if err := downloadSnapshot(backend, key, file); err != nil {
+							if err == errSnapshotNotFound {
+								return fmt.Errorf("no snapshot found for %s in %s", key, backend)
+							}
+
+							return err
+						}
+						defer os.Remove(file)
+
+						if store == nil {
+							kept, err := restoreSnapshot(root, file)
+							if err != nil {
+								return err
+							}
+
+							color.New(color.FgGreen).Fprintf(c.App.Writer, "restored snapshot %s to ./%s (kept %d linked project(s))\n", key, NodeModules, kept)
+							return nil
+						}
+
+						if err := store.Import(key, file, func(header *tar.Header) bool {
+							return isProjectLinkHeader(root, header)
+						}); err != nil {
+							return err
+						}
+					}
+
+					kept, err := replaceNodeModules(root, func(tmp string) error {
+						return store.Materialise(key, tmp)
+					})
+
+					if err != nil {
+						return err
+					}
+
+					color.New(color.FgGreen).Fprintf(c.App.Writer, "restored snapshot %s to ./%s from %s (kept %d linked project(s))\n", key, NodeModules, store.Directory, kept)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func snapshotArgs(c *cli.Context, command string) (string, string, SnapshotBackend, error) {
+	if c.NArg() != 1 {
+		return "", "", nil, fmt.Errorf("triforce snapshot %s requires a root meta or monorepo folder as an argument", command)
+	}
+
+	root, err := filepath.Abs(c.Args().First())
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	manifestPath := c.String("manifest")
+	if manifestPath == "" {
+		manifestPath = filepath.Join(root, PackageJSON)
+	}
+
+	key, err := snapshotKey(manifestPath)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	location := c.String("url")
+	if location == "" {
+		location = c.String("directory")
+	}
+
+	if location == "" {
+		location = filepath.Join(os.Getenv("HOME"), ".triforce", "snapshots")
+	}
+
+	backend, err := newSnapshotBackend(location, S3Config{
+		Endpoint:        c.String("s3-endpoint"),
+		Region:          c.String("s3-region"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	})
+
+	return root, key, backend, err
+}
+
+// snapshotKey hashes the assembled package.json file together with the node version and platform, since
+// native modules built for one of them will not work with another
+func snapshotKey(manifestPath string) (string, error) {
+	manifest, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := exec.Command("node", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the node version: %s", err)
+	}
+
+	hash := sha256.New()
+	hash.Write(manifest)
+	fmt.Fprintf(hash, "\n%s\n%s-%s\n", strings.TrimSpace(string(version)), runtime.GOOS, runtime.GOARCH)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// saveSnapshot archives the node_modules folder at the root into the given file, leaving out the links to
+// local projects created by triforce link
+func saveSnapshot(root, file string) error {
+	nodeModules := filepath.Join(root, NodeModules)
+	if _, err := os.Stat(nodeModules); err != nil {
+		return fmt.Errorf("no node_modules folder found at %s", root)
+	}
+
+	archive, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	err = writeArchive(archive, nodeModules, func(path string, info os.FileInfo) bool {
+		return isProjectLink(root, path)
+	})
+
+	if err != nil {
+		archive.Close()
+		return err
+	}
+
+	return archive.Close()
+}
+
+// restoreSnapshot replaces the node_modules folder at the root with the contents of a snapshot, keeping
+// the links to local projects that were already in place, and returns the number of links kept
+func restoreSnapshot(root, file string) (int, error) {
+	return replaceNodeModules(root, func(tmp string) error {
+		archive, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+
+		err = extractArchive(archive, tmp, func(header *tar.Header) bool {
+			return isProjectLinkHeader(root, header)
+		})
+
+		if err != nil {
+			return fmt.Errorf("could not extract %s: %s", file, err)
+		}
+
+		return nil
+	})
+}
+
+// replaceNodeModules populates a node_modules folder in a temporary folder next to the one at the root,
+// moves the links to local projects across and then swaps it in, returning the number of links kept
+func replaceNodeModules(root string, populate func(tmp string) error) (int, error) {
+	nodeModules := filepath.Join(root, NodeModules)
+	links, err := projectLinks(root, nodeModules)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := ioutil.TempDir(root, ".triforce-restore")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := populate(tmp); err != nil {
+		return 0, err
+	}
+
+	restored := filepath.Join(tmp, NodeModules)
+	if err := os.MkdirAll(restored, os.FileMode(0755)); err != nil {
+		return 0, err
+	}
+
+	for name, target := range links {
+		path := filepath.Join(restored, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			return 0, err
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return 0, err
+		}
+
+		if err := os.Symlink(target, path); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := os.RemoveAll(nodeModules); err != nil {
+		return 0, err
+	}
+
+	return len(links), os.Rename(restored, nodeModules)
+}
+
+// projectLinks returns the targets of the top level links in a node_modules folder that point to local
+// projects, keyed by the name they are linked as
+func projectLinks(root, nodeModules string) (map[string]string, error) {
+	links := make(map[string]string)
+
+	installed, err := readInstalledPackages(nodeModules)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return links, nil
+		}
+
+		return nil, err
+	}
+
+	for name, pkg := range installed {
+		if pkg.Linked && isProjectLink(root, pkg.Path) {
+			if links[name], err = os.Readlink(pkg.Path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return links, nil
+}
+
+// isProjectLink checks if a path is a symlink to a folder inside of the root that is not itself inside of
+// the node_modules folder at the root, which is how triforce link links local projects
+func isProjectLink(root, path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+
+	return isProjectLinkTarget(root, path, target)
+}
+
+// isProjectLinkHeader checks if an archive entry is a link to a local project, which snapshots made by other
+// tools might contain
+func isProjectLinkHeader(root string, header *tar.Header) bool {
+	return header.Typeflag == tar.TypeSymlink && isProjectLinkTarget(root, filepath.Join(root, filepath.FromSlash(header.Name)), header.Linkname)
+}
+
+func isProjectLinkTarget(root, path, target string) bool {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	return rel != NodeModules && !strings.HasPrefix(rel, NodeModules+string(filepath.Separator))
+}
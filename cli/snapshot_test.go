@@ -0,0 +1,593 @@
+package cli_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// objectServer is an in-memory stand-in for snapshot storage over HTTP and S3 compatible APIs
+type objectServer struct {
+	sync.Mutex
+	Objects    map[string][]byte
+	Uploads    map[string]map[int][]byte
+	Parts      []int
+	Ranges     []string
+	Authorized []string
+	Truncate   bool
+	FailPart   int
+	Forbid     bool
+}
+
+// serveMultipart handles the requests of S3 multipart uploads
+func (s *objectServer) serveMultipart(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("uploadId")
+	if id == "" {
+		id = fmt.Sprintf("upload-%d", len(s.Uploads)+1)
+		s.Uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+		return
+	}
+
+	parts, ok := s.Uploads[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		number, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if number == s.FailPart {
+			// simulate a dropped connection while uploading a part
+			s.FailPart = 0
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		parts[number] = body
+		s.Parts = append(s.Parts, number)
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha256.Sum256(body)))
+	case http.MethodGet:
+		fmt.Fprint(w, "<ListPartsResult>")
+		for number := 1; number <= len(parts); number++ {
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%x"</ETag><Size>%d</Size></Part>`, number, sha256.Sum256(parts[number]), len(parts[number]))
+		}
+		fmt.Fprint(w, "</ListPartsResult>")
+	case http.MethodPost:
+		var object []byte
+		for number := 1; number <= len(parts); number++ {
+			object = append(object, parts[number]...)
+		}
+
+		s.Objects[r.URL.Path] = object
+		delete(s.Uploads, id)
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case http.MethodDelete:
+		delete(s.Uploads, id)
+	}
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Authorized = append(s.Authorized, r.Header.Get("Authorization"))
+
+	query := r.URL.Query()
+	if _, ok := query["uploads"]; ok || query.Get("uploadId") != "" {
+		s.serveMultipart(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		s.Objects[r.URL.Path] = body
+	case http.MethodGet:
+		object, ok := s.Objects[r.URL.Path]
+		if !ok && s.Forbid {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if rng := r.Header.Get("Range"); rng != "" {
+			s.Ranges = append(s.Ranges, rng)
+
+			var offset int
+			fmt.Sscanf(rng, "bytes=%d-", &offset)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(object)-1, len(object)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(object[offset:])
+			return
+		}
+
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(object)))
+		if s.Truncate && strings.HasSuffix(r.URL.Path, ".tar.gz") {
+			// simulate a dropped connection half way through the download
+			s.Truncate = false
+			w.Write(object[:len(object)/2])
+			return
+		}
+
+		w.Write(object)
+	}
+}
+
+// archiveEntry is a tar entry of a hand-crafted snapshot, a folder with the given mode if its name ends in a
+// slash, a symlink if Link is set and a file otherwise
+type archiveEntry struct {
+	Name string
+	Link string
+	Mode int64
+}
+
+// replaceSnapshots overwrites every snapshot in a directory with an archive of the given entries, along with
+// a matching checksum so that only the contents of the archive can make restoring it fail
+func replaceSnapshots(directory string, entries ...archiveEntry) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	Expect(tw.WriteHeader(&tar.Header{Name: "node_modules/", Typeflag: tar.TypeDir, Mode: 0755})).To(Succeed())
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name, "/") {
+			Expect(tw.WriteHeader(&tar.Header{Name: entry.Name, Typeflag: tar.TypeDir, Mode: entry.Mode})).To(Succeed())
+			continue
+		}
+
+		if entry.Link != "" {
+			Expect(tw.WriteHeader(&tar.Header{Name: entry.Name, Typeflag: tar.TypeSymlink, Linkname: entry.Link, Mode: 0777})).To(Succeed())
+			continue
+		}
+
+		Expect(tw.WriteHeader(&tar.Header{Name: entry.Name, Typeflag: tar.TypeReg, Mode: 0644, Size: 5})).To(Succeed())
+		_, err := tw.Write([]byte("pwned"))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+
+	archives, err := filepath.Glob(filepath.Join(directory, "*.tar.gz"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(archives).NotTo(BeEmpty())
+
+	for _, archive := range archives {
+		Expect(ioutil.WriteFile(archive, buf.Bytes(), os.FileMode(0644))).To(Succeed())
+		checksum := fmt.Sprintf("%x\n", sha256.Sum256(buf.Bytes()))
+		Expect(ioutil.WriteFile(archive+".sha256", []byte(checksum), os.FileMode(0644))).To(Succeed())
+	}
+}
+
+var _ = Describe("Snapshot", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+	var snapshots string
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["api-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.0.0").Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+		snapshots = filepath.Join(t.RootFolder, ".snapshots")
+
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.0.0").Build())).To(Succeed())
+		Expect(t.Install("dep-a", "1.2.0")).To(Succeed())
+
+		nodeModules := filepath.Join(t.RootFolder, "node_modules")
+		Expect(ioutil.WriteFile(filepath.Join(nodeModules, "dep-a", "cli.js"), []byte("#!/usr/bin/env node\n"), os.FileMode(0755))).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(nodeModules, ".bin"), os.FileMode(0755))).To(Succeed())
+		Expect(os.Symlink("../dep-a/cli.js", filepath.Join(nodeModules, ".bin", "dep-a"))).To(Succeed())
+		Expect(os.Symlink("../api-1", filepath.Join(nodeModules, "api-1"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should throw an error if there is no snapshot for the assembled package.json file", func() {
+		args := []string{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).NotTo(Succeed())
+	})
+
+	It("should restore the node_modules folder with its symlinks and permissions", func() {
+		args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("saved snapshot"))
+
+		nodeModules := filepath.Join(t.RootFolder, "node_modules")
+		Expect(os.RemoveAll(nodeModules)).To(Succeed())
+
+		args = []string{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		info, err := os.Stat(filepath.Join(nodeModules, "dep-a", "cli.js"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+		link, err := os.Readlink(filepath.Join(nodeModules, ".bin", "dep-a"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(link).To(Equal("../dep-a/cli.js"))
+
+		By("leaving the links to local projects out of the snapshot", func() {
+			_, err := os.Lstat(filepath.Join(nodeModules, "api-1"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	It("should keep the links to local projects that are already in place", func() {
+		args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		nodeModules := filepath.Join(t.RootFolder, "node_modules")
+		Expect(os.RemoveAll(filepath.Join(nodeModules, "dep-a"))).To(Succeed())
+
+		args = []string{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("kept 1 linked project(s)"))
+
+		_, err := os.Stat(filepath.Join(nodeModules, "dep-a", "package.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		link, err := os.Readlink(filepath.Join(nodeModules, "api-1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(link).To(Equal("../api-1"))
+	})
+
+	It("should not restore a snapshot saved for a different assembled package.json file", func() {
+		args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.1.0").Build())).To(Succeed())
+
+		args = []string{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder}
+		Expect(app.Run(args)).NotTo(Succeed())
+	})
+
+	Context("malicious snapshots", func() {
+		var outside string
+
+		BeforeEach(func() {
+			outside, err = ioutil.TempDir("", "triforce-outside")
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(outside)).To(Succeed())
+		})
+
+		It("should refuse symlinks that point outside of node_modules", func() {
+			for _, target := range []string{outside, "../../../.." + outside, "dep-a/../../../.." + outside} {
+				replaceSnapshots(snapshots, archiveEntry{Name: "node_modules/evil", Link: target}, archiveEntry{Name: "node_modules/evil/pwned"})
+
+				args := []string{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder}
+				Expect(app.Run(args)).To(MatchError(ContainSubstring("refusing the symlink node_modules/evil to " + target)))
+				Expect(filepath.Join(outside, "pwned")).NotTo(BeAnExistingFile())
+			}
+
+			By("leaving the node_modules folder untouched", func() {
+				Expect(filepath.Join(t.RootFolder, "node_modules", "dep-a", "package.json")).To(BeAnExistingFile())
+			})
+		})
+
+		It("should refuse entries that are written through a symlink in the archive", func() {
+			replaceSnapshots(snapshots, archiveEntry{Name: "node_modules/dep-a/cli.js"}, archiveEntry{Name: "node_modules/evil", Link: "dep-a"}, archiveEntry{Name: "node_modules/evil/cli.js"})
+
+			args := []string{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder}
+			Expect(app.Run(args)).To(MatchError(ContainSubstring("refusing to write node_modules/evil/cli.js through the symlink node_modules/evil")))
+		})
+	})
+
+	Context("remote storage", func() {
+		var objects *objectServer
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			objects = &objectServer{Objects: make(map[string][]byte), Uploads: make(map[string]map[int][]byte)}
+			server = httptest.NewServer(objects)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should resume an interrupted download over http", func() {
+			url := server.URL + "/snapshots"
+			args := []string{"triforce", "snapshot", "save", "--url", url, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(objects.Objects).To(HaveLen(2))
+
+			Expect(os.RemoveAll(filepath.Join(t.RootFolder, "node_modules"))).To(Succeed())
+			objects.Truncate = true
+
+			args = []string{"triforce", "snapshot", "restore", "--url", url, t.RootFolder}
+			Expect(app.Run(args)).NotTo(Succeed())
+			Expect(app.Run(args)).To(Succeed())
+
+			Expect(objects.Ranges).To(HaveLen(1))
+			_, err := os.Stat(filepath.Join(t.RootFolder, "node_modules", "dep-a", "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should refuse to restore a snapshot that does not match its checksum", func() {
+			url := server.URL + "/snapshots"
+			args := []string{"triforce", "snapshot", "save", "--url", url, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			for path, object := range objects.Objects {
+				if strings.HasSuffix(path, ".tar.gz") {
+					objects.Objects[path] = append(object, 0)
+				}
+			}
+
+			args = []string{"triforce", "snapshot", "restore", "--url", url, t.RootFolder}
+			err := app.Run(args)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("checksum mismatch"))
+		})
+
+		It("should save and restore snapshots in an S3 compatible bucket", func() {
+			os.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+			os.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+			defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+			defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+			args := []string{"triforce", "snapshot", "save", "--url", "s3://bucket/snapshots", "--s3-endpoint", server.URL, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			for path := range objects.Objects {
+				Expect(path).To(HavePrefix("/bucket/snapshots/"))
+			}
+
+			Expect(os.RemoveAll(filepath.Join(t.RootFolder, "node_modules"))).To(Succeed())
+
+			args = []string{"triforce", "snapshot", "restore", "--url", "s3://bucket/snapshots", "--s3-endpoint", server.URL, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			for _, authorization := range objects.Authorized {
+				Expect(authorization).To(HavePrefix("AWS4-HMAC-SHA256 Credential=test-key/"))
+			}
+
+			_, err := os.Stat(filepath.Join(t.RootFolder, "node_modules", "dep-a", "package.json"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should resume an interrupted upload to an S3 compatible bucket", func() {
+			os.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+			os.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+			defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+			defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+			// random bytes do not compress, so the snapshot is large enough to be uploaded in two parts
+			blob := make([]byte, 9<<20)
+			rand.New(rand.NewSource(1)).Read(blob)
+			Expect(ioutil.WriteFile(filepath.Join(t.RootFolder, "node_modules", "dep-a", "blob.bin"), blob, os.FileMode(0644))).To(Succeed())
+			objects.FailPart = 2
+
+			args := []string{"triforce", "snapshot", "save", "--url", "s3://bucket/snapshots", "--s3-endpoint", server.URL, t.RootFolder}
+			Expect(app.Run(args)).To(MatchError(ContainSubstring("run the command again to resume")))
+			Expect(app.Run(args)).To(Succeed())
+			Expect(objects.Parts).To(Equal([]int{1, 2}))
+
+			Expect(os.RemoveAll(filepath.Join(t.RootFolder, "node_modules"))).To(Succeed())
+
+			args = []string{"triforce", "snapshot", "restore", "--url", "s3://bucket/snapshots", "--s3-endpoint", server.URL, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			restored, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "node_modules", "dep-a", "blob.bin"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored).To(Equal(blob))
+		})
+
+		It("should report a missing snapshot in an S3 compatible bucket that forbids listing", func() {
+			os.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+			os.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+			defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+			defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+			objects.Forbid = true
+
+			args := []string{"triforce", "snapshot", "restore", "--url", "s3://bucket/snapshots", "--s3-endpoint", server.URL, t.RootFolder}
+			Expect(app.Run(args)).To(MatchError(ContainSubstring("no snapshot found")))
+		})
+	})
+
+	Context("content-addressed store", func() {
+		var store string
+
+		BeforeEach(func() {
+			store = filepath.Join(t.RootFolder, ".store")
+		})
+
+		It("should hardlink the files of a restored snapshot from the store", func() {
+			args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			nodeModules := filepath.Join(t.RootFolder, "node_modules")
+			Expect(os.RemoveAll(filepath.Join(nodeModules, "dep-a"))).To(Succeed())
+
+			args = []string{"triforce", "snapshot", "restore", "--directory", snapshots, "--store", store, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			restored, err := os.Stat(filepath.Join(nodeModules, "dep-a", "cli.js"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			objects, err := filepath.Glob(filepath.Join(store, "files", "*", "*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(objects).To(HaveLen(2))
+
+			linked := false
+			for _, object := range objects {
+				info, err := os.Stat(object)
+				Expect(err).NotTo(HaveOccurred())
+				linked = linked || os.SameFile(info, restored)
+			}
+			Expect(linked).To(BeTrue())
+
+			link, err := os.Readlink(filepath.Join(nodeModules, "api-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("../api-1"))
+
+			By("restoring from the store without the snapshot being available", func() {
+				Expect(os.RemoveAll(snapshots)).To(Succeed())
+				Expect(os.RemoveAll(nodeModules)).To(Succeed())
+
+				Expect(app.Run(args)).To(Succeed())
+
+				_, err := os.Stat(filepath.Join(nodeModules, "dep-a", "package.json"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		It("should refuse to import or materialise snapshots with entries that escape node_modules", func() {
+			outside, err := ioutil.TempDir("", "triforce-outside")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(outside)
+
+			args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			args = []string{"triforce", "snapshot", "restore", "--directory", snapshots, "--store", store, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			By("refusing an index in the store that links outside of node_modules", func() {
+				objects, err := filepath.Glob(filepath.Join(store, "files", "*", "*"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(objects).NotTo(BeEmpty())
+
+				index := fmt.Sprintf(`{"entries": [
+					{"path": "node_modules", "type": "dir", "mode": 493},
+					{"path": "node_modules/evil", "type": "symlink", "target": "%s"},
+					{"path": "node_modules/evil/pwned", "type": "file", "mode": 420, "object": "%s"}
+				]}`, outside, filepath.Base(objects[0]))
+
+				indexes, err := filepath.Glob(filepath.Join(store, "snapshots", "*.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(indexes).To(HaveLen(1))
+				Expect(ioutil.WriteFile(indexes[0], []byte(index), os.FileMode(0644))).To(Succeed())
+
+				Expect(app.Run(args)).To(MatchError(ContainSubstring("refusing the symlink node_modules/evil to " + outside)))
+				Expect(filepath.Join(outside, "pwned")).NotTo(BeAnExistingFile())
+				Expect(os.RemoveAll(filepath.Join(store, "snapshots"))).To(Succeed())
+			})
+
+			By("refusing an index in the store with objects that were not written by the store", func() {
+				Expect(app.Run([]string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder})).To(Succeed())
+				Expect(app.Run(args)).To(Succeed())
+
+				indexes, err := filepath.Glob(filepath.Join(store, "snapshots", "*.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(indexes).To(HaveLen(1))
+
+				for _, object := range []string{"", "a", "../../../../../../etc/passwd"} {
+					index := fmt.Sprintf(`{"entries": [
+						{"path": "node_modules", "type": "dir", "mode": 493},
+						{"path": "node_modules/pwned", "type": "file", "mode": 420, "object": "%s"}
+					]}`, object)
+					Expect(ioutil.WriteFile(indexes[0], []byte(index), os.FileMode(0644))).To(Succeed())
+
+					Expect(app.Run(args)).To(MatchError(ContainSubstring("invalid store object")))
+					Expect(app.Run([]string{"triforce", "store", "gc", "--store", store})).To(MatchError(ContainSubstring("invalid store object")))
+				}
+
+				Expect(os.RemoveAll(filepath.Join(store, "snapshots"))).To(Succeed())
+			})
+
+			By("refusing to import an archive that writes through a symlink", func() {
+				replaceSnapshots(snapshots, archiveEntry{Name: "node_modules/evil", Link: outside}, archiveEntry{Name: "node_modules/evil/pwned"})
+
+				Expect(app.Run(args)).To(MatchError(ContainSubstring("refusing the symlink node_modules/evil to " + outside)))
+				Expect(filepath.Join(outside, "pwned")).NotTo(BeAnExistingFile())
+
+				indexes, err := filepath.Glob(filepath.Join(store, "snapshots", "*.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(indexes).To(BeEmpty())
+			})
+		})
+
+		It("should restore files into folders that are archived as read-only", func() {
+			args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			replaceSnapshots(snapshots, archiveEntry{Name: "node_modules/read-only/", Mode: 0555}, archiveEntry{Name: "node_modules/read-only/index.js"})
+
+			readOnly := filepath.Join(t.RootFolder, "node_modules", "read-only")
+			defer os.Chmod(readOnly, os.FileMode(0755))
+
+			for _, args := range [][]string{
+				{"triforce", "snapshot", "restore", "--directory", snapshots, t.RootFolder},
+				{"triforce", "snapshot", "restore", "--directory", snapshots, "--store", store, t.RootFolder},
+			} {
+				// the folder restored by the previous run has to be writable again to be replaced
+				if _, err := os.Stat(readOnly); err == nil {
+					Expect(os.Chmod(readOnly, os.FileMode(0755))).To(Succeed())
+				}
+
+				Expect(app.Run(args)).To(Succeed())
+				Expect(filepath.Join(readOnly, "index.js")).To(BeAnExistingFile())
+
+				info, err := os.Stat(readOnly)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0555)))
+			}
+		})
+
+		It("should remove the snapshots and files that no assembled package.json file references", func() {
+			args := []string{"triforce", "snapshot", "save", "--directory", snapshots, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			args = []string{"triforce", "snapshot", "restore", "--directory", snapshots, "--store", store, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+
+			args = []string{"triforce", "store", "gc", "--store", store, t.RootFolder}
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("removed 0 snapshot(s) and 0 file(s)"))
+
+			Expect(t.WriteManifest(NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.1.0").Build())).To(Succeed())
+
+			inProgress := filepath.Join(store, "files", ".object123")
+			Expect(ioutil.WriteFile(inProgress, []byte("partial"), os.FileMode(0644))).To(Succeed())
+
+			Expect(app.Run(args)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("removed 1 snapshot(s) and 2 file(s)"))
+			Expect(inProgress).To(BeAnExistingFile())
+
+			objects, err := filepath.Glob(filepath.Join(store, "files", "*", "*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(objects).To(BeEmpty())
+		})
+	})
+})
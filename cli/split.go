@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+const SplitJSON = "triforce-split.json"
+
+type Declaration struct {
+	Project string
+	Version string
+	Dev     bool
+}
+
+// SplitManifest lists the dependencies that a project needs installed in its own node_modules folder
+type SplitManifest struct {
+	Dependencies    map[string]string `json:"dependencies,omitempty"`
+	DevDependencies map[string]string `json:"devDependencies,omitempty"`
+}
+
+func collectDeclarations(projects []*Project, exclude []string) map[string][]Declaration {
+	declarations := make(map[string][]Declaration)
+	for _, p := range projects {
+		for _, field := range []string{"dependencies", "devDependencies"} {
+			for dep, version := range p.Dependencies(field) {
+				if isAPrivateDependency(version, exclude...) {
+					continue
+				}
+
+				declarations[dep] = append(declarations[dep], Declaration{
+					Project: p.Name,
+					Version: version,
+					Dev:     field == "devDependencies",
+				})
+			}
+		}
+	}
+
+	for dep := range declarations {
+		sort.SliceStable(declarations[dep], func(i, j int) bool {
+			return declarations[dep][i].Project < declarations[dep][j].Project
+		})
+	}
+
+	return declarations
+}
+
+func sortedDependencyNames(declarations map[string][]Declaration) []string {
+	var names []string
+	for dep := range declarations {
+		names = append(names, dep)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// splitMajorVersions keeps the major version required by the most projects in the assembled package.json
+// file, and moves the declarations of every other major version into per-project manifests
+func splitMajorVersions(t *TriforcePackageJSON, declarations map[string][]Declaration) map[string]*SplitManifest {
+	plan := make(map[string]*SplitManifest)
+
+	for _, dep := range sortedDependencyNames(declarations) {
+		byMajor := make(map[int][]Declaration)
+		for _, d := range declarations[dep] {
+			if major, ok := rangeMajor(d.Version); ok {
+				byMajor[major] = append(byMajor[major], d)
+			}
+		}
+
+		if len(byMajor) < 2 {
+			continue
+		}
+
+		majority := -1
+		for major, ds := range byMajor {
+			count, majorityCount := countProjects(ds), countProjects(byMajor[majority])
+			if count > majorityCount || (count == majorityCount && major > majority) {
+				majority = major
+			}
+		}
+
+		version := byMajor[majority][0].Version
+		for _, d := range byMajor[majority][1:] {
+			if isHigherRange(version, d.Version) {
+				version = d.Version
+			}
+		}
+
+		if _, ok := t.Dependencies[dep]; ok {
+			t.Dependencies[dep] = version
+		} else {
+			t.DevDependencies[dep] = version
+		}
+
+		for major, ds := range byMajor {
+			if major == majority {
+				continue
+			}
+
+			for _, d := range ds {
+				if _, ok := plan[d.Project]; !ok {
+					plan[d.Project] = &SplitManifest{Dependencies: make(map[string]string), DevDependencies: make(map[string]string)}
+				}
+
+				depType := "dependency"
+				if d.Dev {
+					depType = "devDependency"
+					plan[d.Project].DevDependencies[dep] = d.Version
+				} else {
+					plan[d.Project].Dependencies[dep] = d.Version
+				}
+
+				color.Magenta(split(depType, dep, d.Version, d.Project, version))
+			}
+		}
+	}
+
+	return plan
+}
+
+func countProjects(declarations []Declaration) int {
+	projects := make(map[string]bool)
+	for _, d := range declarations {
+		projects[d.Project] = true
+	}
+
+	return len(projects)
+}
+
+// unsplitDeclarations returns the declarations that were not moved into a project-local install by a plan
+func unsplitDeclarations(declarations map[string][]Declaration, plan map[string]*SplitManifest) map[string][]Declaration {
+	remaining := make(map[string][]Declaration)
+	for dep, ds := range declarations {
+		for _, d := range ds {
+			if manifest, ok := plan[d.Project]; ok {
+				if _, ok := manifest.Dependencies[dep]; ok && !d.Dev {
+					continue
+				}
+
+				if _, ok := manifest.DevDependencies[dep]; ok && d.Dev {
+					continue
+				}
+			}
+
+			remaining[dep] = append(remaining[dep], d)
+		}
+	}
+
+	return remaining
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// ProjectStatus describes the git checkout of a local project and where it is linked
+type ProjectStatus struct {
+	Project  string
+	Git      bool
+	Branch   string
+	Changes  int
+	Upstream string
+	Ahead    string
+	Behind   string
+	Linked   []string
+}
+
+func Status() cli.Command {
+	return cli.Command{
+		Name:  "status",
+		Usage: "shows the branch, uncommitted changes, upstream divergence and links of every local project",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "branch, b", Usage: "fail if any project that is a git checkout is not on this branch"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce status requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			folders, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, folders)
+			if err != nil {
+				return err
+			}
+
+			directories := []string{root}
+			config, err := loadConfig(root)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range config.GroupNames() {
+				directories = append(directories, filepath.Join(root, config.Groups[name].Directory))
+			}
+
+			var statuses []*ProjectStatus
+			for _, p := range projects {
+				status, err := projectStatus(root, p, directories)
+				if err != nil {
+					return err
+				}
+
+				statuses = append(statuses, status)
+			}
+
+			tw := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "PROJECT\tBRANCH\tCHANGES\tUPSTREAM\tAHEAD\tBEHIND\tLINKED")
+			for _, s := range statuses {
+				linked := "-"
+				if len(s.Linked) > 0 {
+					linked = strings.Join(s.Linked, ", ")
+				}
+
+				if !s.Git {
+					fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t%s\n", s.Project, refNotGitDir, linked)
+					continue
+				}
+
+				changes := "clean"
+				if s.Changes > 0 {
+					changes = fmt.Sprintf("%d uncommitted", s.Changes)
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Project, s.Branch, changes, s.Upstream, s.Ahead, s.Behind, linked)
+			}
+
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			expected := c.String("branch")
+			if expected == "" {
+				return nil
+			}
+
+			var misaligned []string
+			for _, s := range statuses {
+				if s.Git && s.Branch != expected {
+					misaligned = append(misaligned, s.Project)
+					color.New(color.FgRed).Fprintf(c.App.Writer, "%s is on %s instead of %s\n", s.Project, s.Branch, expected)
+				}
+			}
+
+			if len(misaligned) > 0 {
+				return fmt.Errorf("%d project(s) are not on branch %s: %s", len(misaligned), expected, strings.Join(misaligned, ", "))
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\nall projects are on branch %s\n", expected)
+			return nil
+		},
+	}
+}
+
+// projectStatus inspects the git checkout of a project, if it is one, and looks for links to it in the
+// node_modules folders of the given directories
+func projectStatus(root string, p *Project, directories []string) (*ProjectStatus, error) {
+	status := &ProjectStatus{Project: p.Name, Upstream: "-", Ahead: "-", Behind: "-"}
+
+	for _, directory := range directories {
+		path := filepath.Join(directory, NodeModules, p.Name)
+		if !isLinkTo(path, p.Path) {
+			continue
+		}
+
+		rel, err := filepath.Rel(root, filepath.Join(directory, NodeModules))
+		if err != nil {
+			return nil, err
+		}
+
+		status.Linked = append(status.Linked, "./"+filepath.ToSlash(rel))
+	}
+
+	if !isGitCheckout(p.Path) {
+		return status, nil
+	}
+
+	status.Git = true
+
+	branch, err := git(p.Path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	if branch == "HEAD" {
+		commit, err := git(p.Path, "rev-parse", "--short", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+
+		branch = fmt.Sprintf("(detached at %s)", commit)
+	}
+
+	status.Branch = branch
+
+	changes, err := git(p.Path, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	if changes != "" {
+		status.Changes = len(strings.Split(changes, "\n"))
+	}
+
+	// projects without an upstream, such as local branches that were never pushed, have nothing to compare to
+	upstream, err := git(p.Path, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return status, nil
+	}
+
+	counts, err := git(p.Path, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(counts)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("could not parse the divergence of %s from %s: %s", p.Name, upstream, counts)
+	}
+
+	status.Upstream, status.Ahead, status.Behind = upstream, fields[0], fields[1]
+	return status, nil
+}
+
+// isLinkTo checks if a path is a symlink that resolves to the given folder
+func isLinkTo(path, folder string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+
+	return filepath.Clean(target) == filepath.Clean(folder)
+}
@@ -0,0 +1,82 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Status", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	commit := func(dir, file string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, file), []byte(file+"\n"), os.FileMode(0666))).To(Succeed())
+		Expect(gitRun(dir, "add", "--all")).To(Succeed())
+		Expect(gitRun(dir, "commit", "--quiet", "--message", "add "+file)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["api-1"] = NewBasicPackageJSONBuilder().Build()
+		p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+		p["lib-2"] = NewBasicPackageJSONBuilder().Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		// lib-1 is one commit ahead of and one commit behind its upstream, with an uncommitted file
+		lib1 := filepath.Join(t.RootFolder, "lib-1")
+		Expect(gitCommitAll(lib1)).To(Succeed())
+		Expect(gitRun(lib1, "checkout", "--quiet", "-b", "feature")).To(Succeed())
+		Expect(gitRun(lib1, "clone", "--quiet", "--bare", ".", "../.remotes/lib-1.git")).To(Succeed())
+		Expect(gitRun(lib1, "remote", "add", "origin", "../.remotes/lib-1.git")).To(Succeed())
+		commit(lib1, "pushed.md")
+		Expect(gitRun(lib1, "push", "--quiet", "--set-upstream", "origin", "feature")).To(Succeed())
+		Expect(gitRun(lib1, "reset", "--quiet", "--hard", "HEAD~1")).To(Succeed())
+		commit(lib1, "local.md")
+		Expect(ioutil.WriteFile(filepath.Join(lib1, "wip.md"), []byte("wip\n"), os.FileMode(0666))).To(Succeed())
+		Expect(os.Symlink("../lib-1", filepath.Join(t.RootFolder, "node_modules", "lib-1"))).To(Succeed())
+
+		// lib-2 is on a branch without an upstream
+		lib2 := filepath.Join(t.RootFolder, "lib-2")
+		Expect(gitCommitAll(lib2)).To(Succeed())
+		Expect(gitRun(lib2, "checkout", "--quiet", "-b", "release")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should show the branch, changes, upstream divergence and links of every project", func() {
+		args := []string{"triforce", "status", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		Expect(out.String()).To(MatchRegexp(`api-1\s+not a git checkout\s+-\s+-\s+-\s+-\s+-\n`))
+		Expect(out.String()).To(MatchRegexp(`lib-1\s+feature\s+1 uncommitted\s+origin/feature\s+1\s+1\s+\./node_modules\n`))
+		Expect(out.String()).To(MatchRegexp(`lib-2\s+release\s+clean\s+-\s+-\s+-\s+-\n`))
+	})
+
+	It("should flag projects that are not on the expected branch", func() {
+		args := []string{"triforce", "status", "--branch", "feature", t.RootFolder}
+		Expect(app.Run(args)).To(MatchError("1 project(s) are not on branch feature: lib-2"))
+		Expect(out.String()).To(ContainSubstring("lib-2 is on release instead of feature"))
+
+		out.Reset()
+		args = []string{"triforce", "status", "--branch", "feature", "--filter", "lib-1", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("all projects are on branch feature"))
+	})
+})
@@ -0,0 +1,380 @@
+package cli
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// PackageStore keeps every file of every restored snapshot once by its content hash, along with an index for
+// each snapshot that lists the files, folders and symlinks needed to materialise its node_modules folder
+type PackageStore struct {
+	Directory string
+}
+
+type StoreIndex struct {
+	Entries []StoreEntry `json:"entries"`
+}
+
+type StoreEntry struct {
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Mode   os.FileMode `json:"mode"`
+	Object string      `json:"object,omitempty"`
+	Target string      `json:"target,omitempty"`
+}
+
+// objectName matches the names that files are stored under, a sha256 hash followed by octal permissions
+var objectName = regexp.MustCompile(`^[0-9a-f]{64}-[0-7]+$`)
+
+const (
+	entryDir     = "dir"
+	entryFile    = "file"
+	entrySymlink = "symlink"
+)
+
+func Store() cli.Command {
+	return cli.Command{
+		Name:  "store",
+		Usage: "manages the content-addressed store that snapshots are restored from",
+		Subcommands: []cli.Command{
+			{
+				Name:      "gc",
+				Usage:     "removes the snapshots and files in the store that are not referenced by the assembled package.json file of the given roots",
+				UsageText: "triforce store gc [command options] [roots...]",
+				Flags: []cli.Flag{
+					cli.StringFlag{Name: "store", Usage: "directory of the store (defaults to ~/.triforce/store)", EnvVar: "TRIFORCE_STORE"},
+				},
+				Action: func(c *cli.Context) error {
+					store := newStore(c.String("store"))
+
+					// without any roots every snapshot is kept and only unreferenced files are removed
+					var keep map[string]bool
+					if c.NArg() > 0 {
+						keep = make(map[string]bool)
+						for _, arg := range c.Args() {
+							root, err := filepath.Abs(arg)
+							if err != nil {
+								return err
+							}
+
+							key, err := snapshotKey(filepath.Join(root, PackageJSON))
+							if err != nil {
+								return err
+							}
+
+							keep[key] = true
+						}
+					}
+
+					snapshots, files, size, err := store.GC(keep)
+					if err != nil {
+						return err
+					}
+
+					color.New(color.FgGreen).Fprintf(c.App.Writer, "removed %d snapshot(s) and %d file(s) from %s, reclaiming %s\n", snapshots, files, store.Directory, humanBytes(size))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func newStore(directory string) *PackageStore {
+	if directory == "" {
+		directory = filepath.Join(os.Getenv("HOME"), ".triforce", "store")
+	}
+
+	return &PackageStore{Directory: directory}
+}
+
+func (s *PackageStore) indexPath(key string) string {
+	return filepath.Join(s.Directory, "snapshots", key+".json")
+}
+
+func (s *PackageStore) objectPath(object string) string {
+	return filepath.Join(s.Directory, "files", object[:2], object)
+}
+
+// checkObject refuses object names in an index that were not written by the store, which could otherwise
+// point anywhere on disk
+func checkObject(entry StoreEntry) error {
+	if !objectName.MatchString(entry.Object) {
+		return fmt.Errorf("refusing %s with the invalid store object \"%s\"", entry.Path, entry.Object)
+	}
+
+	return nil
+}
+
+func (s *PackageStore) Has(key string) bool {
+	_, err := os.Stat(s.indexPath(key))
+	return err == nil
+}
+
+// Import adds the files of a snapshot archive to the store and writes its index, leaving out any entry for
+// which skip returns true and refusing archives with entries that escape the node_modules folder
+func (s *PackageStore) Import(key, file string, skip func(header *tar.Header) bool) error {
+	archive, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if err := os.MkdirAll(filepath.Join(s.Directory, "files"), os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	index := &StoreIndex{}
+	err = readArchive(archive, skip, func(name string, header *tar.Header, content io.Reader) error {
+		entry := StoreEntry{Path: filepath.ToSlash(name), Mode: os.FileMode(header.Mode).Perm()}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.Type = entryDir
+		case tar.TypeSymlink:
+			entry.Type, entry.Target = entrySymlink, header.Linkname
+		case tar.TypeReg:
+			object, err := s.writeObject(content, entry.Mode)
+			if err != nil {
+				return err
+			}
+
+			entry.Type, entry.Object = entryFile, object
+		default:
+			return nil
+		}
+
+		index.Entries = append(index.Entries, entry)
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("could not import %s into the store: %s", file, err)
+	}
+
+	bytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.indexPath(key)), os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	// the index is written last so that a snapshot is only ever found in the store once all of its files are
+	tmp := s.indexPath(key) + ".tmp"
+	if err := ioutil.WriteFile(tmp, bytes, os.FileMode(0644)); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.indexPath(key))
+}
+
+// writeObject stores the content of a file under its hash and permissions, since hardlinks to the same
+// object share the same permissions
+func (s *PackageStore) writeObject(content io.Reader, mode os.FileMode) (string, error) {
+	tmp, err := ioutil.TempFile(filepath.Join(s.Directory, "files"), ".object")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), content); err != nil {
+		tmp.Close()
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	object := fmt.Sprintf("%s-%o", hex.EncodeToString(hash.Sum(nil)), mode)
+	if _, err := os.Stat(s.objectPath(object)); err == nil {
+		return object, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.objectPath(object)), os.FileMode(0755)); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return "", err
+	}
+
+	return object, os.Rename(tmp.Name(), s.objectPath(object))
+}
+
+// Materialise recreates the node_modules folder of a snapshot in the destination folder by hardlinking every
+// file from the store, copying files instead if the store is on a different device
+func (s *PackageStore) Materialise(key, destination string) error {
+	bytes, err := ioutil.ReadFile(s.indexPath(key))
+	if err != nil {
+		return err
+	}
+
+	index := &StoreIndex{}
+	if err := json.Unmarshal(bytes, index); err != nil {
+		return fmt.Errorf("could not parse %s: %s", s.indexPath(key), err)
+	}
+
+	// the index was built from an archive that may have come from shared storage, so it is checked again
+	links := make(archiveLinks)
+	var folders folderModes
+	for _, entry := range index.Entries {
+		name, err := archivePath(entry.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := links.check(name); err != nil {
+			return err
+		}
+
+		if entry.Type == entrySymlink {
+			if err := links.add(name, entry.Target); err != nil {
+				return err
+			}
+		}
+
+		path := filepath.Join(destination, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+			return err
+		}
+
+		switch entry.Type {
+		case entryDir:
+			if err := os.MkdirAll(path, os.FileMode(0755)); err != nil {
+				return err
+			}
+
+			folders.add(path, entry.Mode)
+		case entrySymlink:
+			if err := os.Symlink(entry.Target, path); err != nil {
+				return err
+			}
+		case entryFile:
+			if err := checkObject(entry); err != nil {
+				return err
+			}
+
+			if err := os.Link(s.objectPath(entry.Object), path); err == nil {
+				continue
+			}
+
+			object, err := os.Open(s.objectPath(entry.Object))
+			if err != nil {
+				return fmt.Errorf("missing %s in the store: %s", entry.Path, err)
+			}
+
+			err = writeFile(path, object, entry.Mode)
+			object.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return folders.apply()
+}
+
+// GC removes the indexes of snapshots that are not kept, or none if keep is nil, and then every file that
+// is no longer referenced by a remaining index, returning the number of indexes and files removed and the
+// size of the files removed. The files of a snapshot being imported are not referenced until its index is
+// written, so GC must not run while a snapshot is being restored into the same store
+func (s *PackageStore) GC(keep map[string]bool) (int, int, int64, error) {
+	referenced := make(map[string]bool)
+	removedSnapshots := 0
+
+	indexes, err := filepath.Glob(filepath.Join(s.Directory, "snapshots", "*.json"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, path := range indexes {
+		key := strings.TrimSuffix(filepath.Base(path), ".json")
+		if keep != nil && !keep[key] {
+			if err := os.Remove(path); err != nil {
+				return 0, 0, 0, err
+			}
+
+			removedSnapshots++
+			continue
+		}
+
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		index := &StoreIndex{}
+		if err := json.Unmarshal(bytes, index); err != nil {
+			return 0, 0, 0, fmt.Errorf("could not parse %s: %s", path, err)
+		}
+
+		for _, entry := range index.Entries {
+			if entry.Type != entryFile {
+				continue
+			}
+
+			if err := checkObject(entry); err != nil {
+				return 0, 0, 0, fmt.Errorf("could not parse %s: %s", path, err)
+			}
+
+			referenced[entry.Object] = true
+		}
+	}
+
+	removedFiles := 0
+	var removedSize int64
+
+	err = filepath.Walk(filepath.Join(s.Directory, "files"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		// files still being written by an import are left alone
+		if info.IsDir() || referenced[info.Name()] || strings.HasPrefix(info.Name(), ".object") {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		removedFiles++
+		removedSize += info.Size()
+		return nil
+	})
+
+	return removedSnapshots, removedFiles, removedSize, err
+}
+
+func humanBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
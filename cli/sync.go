@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func Sync() cli.Command {
+	return cli.Command{
+		Name:  "sync",
+		Usage: "rewrites the package.json file of each project to use the assembled version of shared dependencies",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "since, s", Usage: "only include projects affected by changes since this git ref"},
+			cli.StringSliceFlag{Name: "only", Usage: "only sync these dependencies", Value: &cli.StringSlice{}},
+			cli.BoolFlag{Name: "dry-run", Usage: "print the changes that would be made without writing them"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce sync requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			projectFolders, err := selectProjectFolders(root, c.StringSlice("filter"), c.String("since"), exclude, c.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, projectFolders)
+			if err != nil {
+				return err
+			}
+
+			resolved := resolveVersions(collectDeclarations(projects, exclude))
+			if only := c.StringSlice("only"); len(only) > 0 {
+				selected := make(map[string]string)
+				for _, dep := range only {
+					if version, ok := resolved[dep]; ok {
+						selected[dep] = version
+					}
+				}
+
+				resolved = selected
+			}
+
+			changedFiles, err := rewriteProjects(c, projects, resolved, exclude, c.Bool("dry-run"))
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("dry-run") {
+				color.New(color.FgGreen).Fprintf(c.App.Writer, "\nwould update %d package.json file(s)\n", changedFiles)
+			} else {
+				color.New(color.FgGreen).Fprintf(c.App.Writer, "\nupdated %d package.json file(s)\n", changedFiles)
+			}
+
+			return nil
+		},
+	}
+}
+
+// resolveVersions picks the highest version of each dependency, whether it was declared as a dependency or
+// a devDependency, so that syncing never downgrades a project
+func resolveVersions(declarations map[string][]Declaration) map[string]string {
+	resolved := make(map[string]string)
+	for dep, ds := range declarations {
+		for _, dev := range []bool{false, true} {
+			for _, d := range ds {
+				if d.Dev != dev {
+					continue
+				}
+
+				if val, ok := resolved[dep]; !ok || isHigherRange(val, d.Version) {
+					resolved[dep] = d.Version
+				}
+			}
+		}
+	}
+
+	return resolved
+}
+
+// rewriteProjects writes the given versions into the package.json file of each project, returning the
+// number of files that were (or with a dry run, would have been) changed
+func rewriteProjects(c *cli.Context, projects []*Project, versions map[string]string, exclude []string, dryRun bool) (int, error) {
+	changedFiles := 0
+
+	for _, p := range projects {
+		changed, err := rewriteProject(c, p, versions, exclude, "synced", dryRun)
+		if err != nil {
+			return changedFiles, err
+		}
+
+		if changed {
+			changedFiles++
+		}
+	}
+
+	return changedFiles, nil
+}
+
+// rewriteProject writes the given versions into the package.json file of a project, describing each
+// change with the given verb
+func rewriteProject(c *cli.Context, p *Project, versions map[string]string, exclude []string, verb string, dryRun bool) (bool, error) {
+	file := filepath.Join(p.Path, PackageJSON)
+	changes, err := rewriteFile(file, file, versions, exclude, dryRun)
+	if err != nil || len(changes) == 0 {
+		return false, err
+	}
+
+	color.New(color.FgGreen).Fprintf(c.App.Writer, "\n./%s/%s\n", p.Name, PackageJSON)
+	for _, change := range changes {
+		fmt.Fprintln(c.App.Writer, change.Describe(verb))
+	}
+
+	return true, nil
+}
+
+// rewriteFile writes a copy of a package.json file with the given versions to the destination
+func rewriteFile(source, destination string, versions map[string]string, exclude []string, dryRun bool) ([]VersionChange, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	rewritten, changes, err := rewriteVersions(original, versions, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("could not rewrite %s: %s", source, err)
+	}
+
+	if dryRun || (len(changes) == 0 && source == destination) {
+		return changes, nil
+	}
+
+	return changes, ioutil.WriteFile(destination, rewritten, info.Mode())
+}
+
+type VersionChange struct {
+	DepType    string
+	Name       string
+	OldVersion string
+	NewVersion string
+}
+
+func (v VersionChange) Describe(verb string) string {
+	return fmt.Sprintf("%s %s \"%s\" from version \"%s\" to \"%s\"", verb, v.DepType, v.Name, v.OldVersion, v.NewVersion)
+}
+
+// rewriteVersions replaces the versions of dependencies and devDependencies in the text of a package.json
+// file, leaving formatting, key order and indentation untouched
+func rewriteVersions(content []byte, versions map[string]string, exclude []string) ([]byte, []VersionChange, error) {
+	var changes []VersionChange
+
+	var deps []string
+	for dep := range versions {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	for _, field := range []string{"dependencies", "devDependencies"} {
+		start, end, ok := topLevelObject(content, field)
+		if !ok {
+			continue
+		}
+
+		depType := "dependency"
+		if field == "devDependencies" {
+			depType = "devDependency"
+		}
+
+		section := content[start:end]
+		for _, dep := range deps {
+			pattern := regexp.MustCompile(`("` + regexp.QuoteMeta(dep) + `"\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+			match := pattern.FindSubmatchIndex(section)
+			if match == nil {
+				continue
+			}
+
+			old := string(section[match[4]:match[5]])
+			if old == versions[dep] || isAPrivateDependency(old, exclude...) {
+				continue
+			}
+
+			value, err := json.Marshal(versions[dep])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			replaced := append(append([]byte{}, section[:match[3]]...), value...)
+			section = append(replaced, section[match[1]:]...)
+			changes = append(changes, VersionChange{DepType: depType, Name: dep, OldVersion: old, NewVersion: versions[dep]})
+		}
+
+		content = append(append(append([]byte{}, content[:start]...), section...), content[end:]...)
+	}
+
+	return content, changes, nil
+}
+
+// topLevelObject returns the start and end offsets of the object stored under a top level key of a
+// JSON document
+func topLevelObject(content []byte, key string) (int, int, bool) {
+	depth := 0
+	lastString := ""
+
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '"':
+			end := endOfString(content, i)
+			lastString = string(content[i+1 : end])
+			i = end
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ':':
+			if depth != 1 || lastString != key {
+				continue
+			}
+
+			j := i + 1
+			for j < len(content) && (content[j] == ' ' || content[j] == '\t' || content[j] == '\n' || content[j] == '\r') {
+				j++
+			}
+
+			if j < len(content) && content[j] == '{' {
+				if end := endOfObject(content, j); end > j {
+					return j, end, true
+				}
+			}
+
+			return 0, 0, false
+		}
+	}
+
+	return 0, 0, false
+}
+
+// endOfString returns the offset of the closing quote of the string starting at the given offset
+func endOfString(content []byte, start int) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return len(content)
+}
+
+// endOfObject returns the offset just after the brace closing the object starting at the given offset
+func endOfObject(content []byte, start int) int {
+	depth := 0
+	for i := start; i < len(content); i++ {
+		switch content[i] {
+		case '"':
+			i = endOfString(content, i)
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
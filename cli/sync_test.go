@@ -0,0 +1,114 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const handFormattedPackageJSON = `{
+    "name": "api-1",
+    "devDependencies": { "dep-b": "~1.0.0", "dep-a": "^1.0.0" },
+    "dependencies": {
+        "lib-1": "github:someorg/lib-1",
+        "dep-a": "^1.0.0"
+    }
+}
+`
+
+var _ = Describe("Sync", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+	var file string
+
+	BeforeEach(func() {
+		p = make(map[string]*BasicPackageJSON)
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p["api-1"] = NewBasicPackageJSONBuilder().Build()
+		p["api-2"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.2.0").
+			Dependency("lib-1", "^2.0.0").
+			DevDependency("dep-b", "~1.1.0").
+			Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		file = filepath.Join(t.RootFolder, "api-1", "package.json")
+		Expect(ioutil.WriteFile(file, []byte(handFormattedPackageJSON), os.FileMode(0666))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should rewrite versions of shared dependencies while preserving formatting and private dependencies", func() {
+		args := []string{"triforce", "sync", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		bytes, err := ioutil.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(bytes)).To(Equal(`{
+    "name": "api-1",
+    "devDependencies": { "dep-b": "~1.1.0", "dep-a": "^1.2.0" },
+    "dependencies": {
+        "lib-1": "github:someorg/lib-1",
+        "dep-a": "^1.2.0"
+    }
+}
+`))
+
+		Expect(out.String()).To(ContainSubstring(`synced dependency "dep-a" from version "^1.0.0" to "^1.2.0"`))
+		Expect(out.String()).To(ContainSubstring(`synced devDependency "dep-b" from version "~1.0.0" to "~1.1.0"`))
+		Expect(out.String()).To(ContainSubstring("updated 1 package.json file(s)"))
+	})
+
+	It("should only rewrite the given dependencies", func() {
+		args := []string{"triforce", "sync", "--only", "dep-b", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		bytes, err := ioutil.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(bytes)).To(ContainSubstring(`{ "dep-b": "~1.1.0", "dep-a": "^1.0.0" }`))
+		Expect(string(bytes)).To(ContainSubstring(`"dep-a": "^1.0.0"
+    }`))
+	})
+
+	It("should not write any changes on a dry run", func() {
+		args := []string{"triforce", "sync", "--dry-run", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		bytes, err := ioutil.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(bytes)).To(Equal(handFormattedPackageJSON))
+		Expect(out.String()).To(ContainSubstring("would update 1 package.json file(s)"))
+	})
+
+	It("should compare versions numerically so that projects are never downgraded", func() {
+		p["api-3"] = NewBasicPackageJSONBuilder().Dependency("dep-c", "^1.9.0").Build()
+		p["api-4"] = NewBasicPackageJSONBuilder().Dependency("dep-c", "^1.10.0").Build()
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		args := []string{"triforce", "sync", "--only", "dep-c", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		bytes, err := ioutil.ReadFile(filepath.Join(t.RootFolder, "api-3", "package.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(bytes)).To(ContainSubstring(`"dep-c": "^1.10.0"`))
+
+		Expect(out.String()).To(ContainSubstring(`synced dependency "dep-c" from version "^1.9.0" to "^1.10.0"`))
+		Expect(out.String()).NotTo(ContainSubstring(`to "^1.9.0"`))
+	})
+})
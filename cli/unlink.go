@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// BackupFolder is where packages that were in the way of a link are kept, next to the node_modules folder
+const BackupFolder = ".triforce-backup"
+
+const (
+	collisionFail    = "fail"
+	collisionSkip    = "skip"
+	collisionReplace = "replace"
+)
+
+// Collision is a package installed in a node_modules folder under the name a local project is linked as
+type Collision struct {
+	Project string
+	Path    string
+	Action  string
+	Backup  string
+}
+
+func Unlink() cli.Command {
+	return cli.Command{
+		Name:  "unlink",
+		Usage: "removes the links to local projects from node_modules and restores the packages they replaced",
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "groups, g", Usage: "unlink the projects linked into the node_modules folder of each group defined in .triforce.json"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce unlink requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			directories := []string{root}
+			if c.Bool("groups") {
+				config, err := loadConfig(root)
+				if err != nil {
+					return err
+				}
+
+				if len(config.Groups) == 0 {
+					return fmt.Errorf("no groups defined in %s", filepath.Join(root, TriforceConfig))
+				}
+
+				directories = nil
+				for _, name := range config.GroupNames() {
+					directories = append(directories, filepath.Join(root, config.Groups[name].Directory))
+				}
+			}
+
+			unlinked, restored := 0, 0
+			for _, directory := range directories {
+				u, r, err := unlinkProjects(c, root, directory)
+				if err != nil {
+					return err
+				}
+
+				unlinked += u
+				restored += r
+			}
+
+			color.New(color.FgGreen).Fprintf(c.App.Writer, "\nremoved %d link(s) and restored %d package(s)\n", unlinked, restored)
+			return nil
+		},
+	}
+}
+
+// unlinkProjects removes the links to local projects from the node_modules folder of a directory and moves
+// the packages backed up when linking back into place, returning the number of links removed and packages
+// restored
+func unlinkProjects(c *cli.Context, root, directory string) (int, int, error) {
+	nodeModules := filepath.Join(directory, NodeModules)
+	links, err := projectLinks(root, nodeModules)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	unlinked := 0
+	for _, name := range sortedKeys(links) {
+		path := filepath.Join(nodeModules, filepath.FromSlash(name))
+		if err := os.Remove(path); err != nil {
+			return 0, 0, err
+		}
+
+		fmt.Fprintf(c.App.Writer, "removed link %s\n", relativeTo(root, path))
+		unlinked++
+	}
+
+	backups := filepath.Join(directory, BackupFolder)
+	entries, err := ioutil.ReadDir(backups)
+	if os.IsNotExist(err) {
+		return unlinked, 0, nil
+	}
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	restored := 0
+	for _, entry := range entries {
+		backup := filepath.Join(backups, entry.Name())
+		path := filepath.Join(nodeModules, entry.Name())
+
+		// a package installed since the backup was made takes precedence over the backup
+		if _, err := os.Lstat(path); err == nil {
+			color.New(color.FgYellow).Fprintf(c.App.Writer, "kept %s in %s since %s exists again\n", entry.Name(), relativeTo(root, backups), relativeTo(root, path))
+			continue
+		}
+
+		if err := os.MkdirAll(nodeModules, os.FileMode(0755)); err != nil {
+			return 0, 0, err
+		}
+
+		if err := os.Rename(backup, path); err != nil {
+			return 0, 0, err
+		}
+
+		fmt.Fprintf(c.App.Writer, "restored %s from %s\n", relativeTo(root, path), relativeTo(root, backup))
+		restored++
+	}
+
+	if entries, err := ioutil.ReadDir(backups); err == nil && len(entries) == 0 {
+		if err := os.Remove(backups); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return unlinked, restored, nil
+}
+
+// findCollisions lists the projects that would be linked over something in node_modules that is not a link,
+// such as a private package that was installed from a registry
+func findCollisions(nodeModules string, projects []*Project) []*Collision {
+	var collisions []*Collision
+	for _, p := range projects {
+		path := filepath.Join(nodeModules, p.Name)
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink == 0 {
+			collisions = append(collisions, &Collision{Project: p.Name, Path: path})
+		}
+	}
+
+	return collisions
+}
+
+func reportCollisions(c *cli.Context, root string, collisions []*Collision) error {
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	color.New(color.FgYellow).Fprintf(c.App.Writer, "\nprojects linked where a package was already installed:\n")
+
+	tw := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tPATH\tACTION")
+	for _, collision := range collisions {
+		action := "skipped"
+		if collision.Action == collisionReplace {
+			action = fmt.Sprintf("replaced (backed up to %s)", relativeTo(root, collision.Backup))
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", collision.Project, relativeTo(root, collision.Path), action)
+	}
+
+	return tw.Flush()
+}
+
+func relativeTo(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return "./" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,60 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unlink", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["lib-1"] = NewBasicPackageJSONBuilder().Build()
+		p["lib-2"] = NewBasicPackageJSONBuilder().Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should remove links to local projects and restore the packages they replaced", func() {
+		Expect(t.Install("lib-1", "1.0.0")).To(Succeed())
+		Expect(t.Install("dep-a", "1.0.0")).To(Succeed())
+
+		args := []string{"triforce", "link", "--on-collision", "replace", t.RootFolder}
+		Expect(cli.App().Run(args)).To(Succeed())
+
+		args = []string{"triforce", "unlink", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+
+		info, err := os.Lstat(filepath.Join(t.RootFolder, "node_modules", "lib-1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode() & os.ModeSymlink).To(BeZero())
+		Expect(filepath.Join(t.RootFolder, "node_modules", "lib-1", "package.json")).To(BeAnExistingFile())
+		Expect(filepath.Join(t.RootFolder, "node_modules", "lib-2")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(t.RootFolder, "node_modules", "dep-a")).To(BeADirectory())
+		Expect(filepath.Join(t.RootFolder, ".triforce-backup")).NotTo(BeAnExistingFile())
+
+		Expect(out.String()).To(ContainSubstring("removed link ./node_modules/lib-1"))
+		Expect(out.String()).To(ContainSubstring("removed link ./node_modules/lib-2"))
+		Expect(out.String()).To(ContainSubstring("restored ./node_modules/lib-1 from ./.triforce-backup/lib-1"))
+		Expect(out.String()).To(ContainSubstring("removed 2 link(s) and restored 1 package(s)"))
+	})
+})
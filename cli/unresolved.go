@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// UnresolvedDependency is a private dependency of a linked project that is neither a local project nor
+// installed in the node_modules folder the project is linked into
+type UnresolvedDependency struct {
+	Project    string
+	Dependency string
+	Version    string
+}
+
+// unresolvedPrivateDependencies finds the private dependencies of the given projects that do not match any
+// project checked out at the root and are not installed in the node_modules folder of the directory
+func unresolvedPrivateDependencies(root, directory string, projects []*Project, exclude []string) ([]UnresolvedDependency, error) {
+	folders, err := getProjectFolders(root, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := loadProjects(root, folders)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newProjectGraph(all, exclude)
+	packageNames := make(map[string]string)
+	for _, p := range all {
+		packageNames[p.PackageName()] = p.Name
+	}
+
+	var unresolved []UnresolvedDependency
+	for _, p := range projects {
+		for _, field := range []string{"dependencies", "devDependencies"} {
+			for dep, version := range p.Dependencies(field) {
+				if !isAPrivateDependency(version, exclude...) {
+					continue
+				}
+
+				if _, ok := g.resolve(dep, version, packageNames, exclude); ok {
+					continue
+				}
+
+				if _, err := os.Stat(filepath.Join(directory, NodeModules, filepath.FromSlash(dep))); err == nil {
+					continue
+				}
+
+				if _, ok := resolvePackage(root, p.Path, dep); ok {
+					continue
+				}
+
+				unresolved = append(unresolved, UnresolvedDependency{Project: p.Name, Dependency: dep, Version: version})
+			}
+		}
+	}
+
+	sort.Slice(unresolved, func(i, j int) bool {
+		if unresolved[i].Project != unresolved[j].Project {
+			return unresolved[i].Project < unresolved[j].Project
+		}
+
+		return unresolved[i].Dependency < unresolved[j].Dependency
+	})
+
+	return unresolved, nil
+}
+
+// reportUnresolved prints a table of the unresolved private dependencies of the projects linked into a
+// directory, returning the number of unresolved dependencies
+func reportUnresolved(c *cli.Context, root, directory string, projects []*Project) (int, error) {
+	unresolved, err := unresolvedPrivateDependencies(root, directory, projects, c.StringSlice("exclude"))
+	if err != nil || len(unresolved) == 0 {
+		return 0, err
+	}
+
+	relative, err := filepath.Rel(root, filepath.Join(directory, NodeModules))
+	if err != nil {
+		return 0, err
+	}
+
+	color.New(color.FgYellow).Fprintf(c.App.Writer, "\nprivate dependencies that are neither checked out nor installed in ./%s:\n", relative)
+
+	tw := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tDEPENDENCY\tVERSION")
+	for _, u := range unresolved {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", u.Project, u.Dependency, u.Version)
+	}
+
+	return len(unresolved), tw.Flush()
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Jeffail/gabs"
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+func VerifyInstall() cli.Command {
+	return cli.Command{
+		Name:  "verify-install",
+		Usage: "verifies the packages installed in node_modules against the assembled package.json file and each project",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "exclude, e", Usage: "patterns to exclude in versions", Value: &cli.StringSlice{"github", "gitlab", "bitbucket"}},
+			cli.StringSliceFlag{Name: "filter, f", Usage: "patterns to include in projects", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "manifest, m", Usage: "assembled package.json file that was installed (defaults to the package.json file at the root)"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("triforce verify-install requires a root meta or monorepo folder as an argument")
+			}
+
+			root, err := filepath.Abs(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			exclude := c.StringSlice("exclude")
+
+			manifestPath := c.String("manifest")
+			if manifestPath == "" {
+				manifestPath = filepath.Join(root, PackageJSON)
+			}
+
+			manifest, err := gabs.ParseJSONFile(manifestPath)
+			if err != nil {
+				return err
+			}
+
+			nodeModules := filepath.Join(root, NodeModules)
+			if _, err := os.Stat(nodeModules); err != nil {
+				return fmt.Errorf("no node_modules folder found at %s", root)
+			}
+
+			installed, err := readInstalledPackages(nodeModules)
+			if err != nil {
+				return err
+			}
+
+			projectFolders, err := getProjectFolders(root, c.StringSlice("filter"))
+			if err != nil {
+				return err
+			}
+
+			projects, err := loadProjects(root, projectFolders)
+			if err != nil {
+				return err
+			}
+
+			v := &verification{}
+			required := v.checkManifest(manifest, installed)
+			v.checkProjects(root, projects, required, exclude)
+			v.checkExtraneous(root, required, installed)
+
+			for _, problem := range v.problems {
+				color.New(color.FgRed).Fprintln(c.App.Writer, problem)
+			}
+
+			fmt.Fprintf(c.App.Writer, "\nverified %d installed package(s): %d missing, %d out of range, %d extraneous\n", len(installed), v.missing, v.outOfRange, v.extraneous)
+
+			if len(v.problems) > 0 {
+				return fmt.Errorf("found %d problem(s) with the packages installed in %s", len(v.problems), nodeModules)
+			}
+
+			return nil
+		},
+	}
+}
+
+type verification struct {
+	problems   []string
+	reported   map[string]bool
+	missing    int
+	outOfRange int
+	extraneous int
+}
+
+func (v *verification) report(problem string) bool {
+	if v.reported == nil {
+		v.reported = make(map[string]bool)
+	}
+
+	if v.reported[problem] {
+		return false
+	}
+
+	v.reported[problem] = true
+	v.problems = append(v.problems, problem)
+	return true
+}
+
+// checkManifest checks that every dependency of the assembled package.json file is installed with a
+// version satisfying its range, returning the names of every dependency that was checked
+func (v *verification) checkManifest(manifest *gabs.Container, installed map[string]*InstalledPackage) []string {
+	var required []string
+
+	for _, field := range []string{"dependencies", "devDependencies"} {
+		data, ok := manifest.Path(field).Data().(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var names []string
+		for dep := range data {
+			names = append(names, dep)
+		}
+		sort.Strings(names)
+
+		for _, dep := range names {
+			version, _ := data[dep].(string)
+			required = append(required, dep)
+
+			pkg, ok := installed[dep]
+			if !ok {
+				if v.report(missing(dep, version, "the assembled package.json")) {
+					v.missing++
+				}
+				continue
+			}
+
+			if !pkg.Linked {
+				if ok, err := satisfies(pkg.Version, version); err == nil && !ok {
+					if v.report(outOfRange(dep, pkg.Version, version, "the assembled package.json")) {
+						v.outOfRange++
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(required)
+	return required
+}
+
+// checkProjects checks the version each project resolves for its public dependencies, looking in the
+// project's own node_modules folder before the one at the root, as node would
+func (v *verification) checkProjects(root string, projects []*Project, required []string, exclude []string) {
+	local := make(map[string]bool)
+	for _, p := range projects {
+		local[p.Name] = true
+		local[p.PackageName()] = true
+	}
+
+	inManifest := make(map[string]bool)
+	for _, dep := range required {
+		inManifest[dep] = true
+	}
+
+	for _, p := range projects {
+		for _, field := range []string{"dependencies", "devDependencies"} {
+			deps := p.Dependencies(field)
+
+			var names []string
+			for dep := range deps {
+				names = append(names, dep)
+			}
+			sort.Strings(names)
+
+			for _, dep := range names {
+				version := deps[dep]
+				if local[dep] || isAPrivateDependency(version, exclude...) {
+					continue
+				}
+
+				dir, ok := resolvePackage(root, p.Path, dep)
+				if !ok {
+					// dependencies of the assembled package.json file have already been reported
+					if !inManifest[dep] && v.report(missing(dep, version, p.Name)) {
+						v.missing++
+					}
+					continue
+				}
+
+				parsed, err := gabs.ParseJSONFile(filepath.Join(dir, PackageJSON))
+				if err != nil {
+					continue
+				}
+
+				installedVersion, _ := parsed.Path("version").Data().(string)
+				if ok, err := satisfies(installedVersion, version); err == nil && !ok {
+					if v.report(outOfRange(dep, installedVersion, version, p.Name)) {
+						v.outOfRange++
+					}
+				}
+			}
+		}
+	}
+}
+
+func (v *verification) checkExtraneous(root string, required []string, installed map[string]*InstalledPackage) {
+	reached := requiredPackages(root, required)
+
+	for _, name := range sortedPackageNames(installed) {
+		pkg := installed[name]
+		if !pkg.Linked && !reached[pkg.Path] {
+			if v.report(extraneous(name, pkg.Version)) {
+				v.extraneous++
+			}
+		}
+	}
+}
+
+func missing(name, version, requiredBy string) string {
+	return fmt.Sprintf("missing package \"%s\" (version \"%s\" required by %s)", name, version, requiredBy)
+}
+
+func outOfRange(name, installedVersion, version, requiredBy string) string {
+	return fmt.Sprintf("out of range package \"%s\" (installed version \"%s\" does not satisfy \"%s\" required by %s)", name, installedVersion, version, requiredBy)
+}
+
+func extraneous(name, installedVersion string) string {
+	return fmt.Sprintf("extraneous package \"%s\" (installed version \"%s\" is not required by the assembled package.json or its dependencies)", name, installedVersion)
+}
@@ -0,0 +1,82 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/LGUG2Z/triforce/cli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifyInstall", func() {
+	var p map[string]*BasicPackageJSON
+	var t *TestSpace
+	var err error
+	var out bytes.Buffer
+	var app = cli.App()
+
+	BeforeEach(func() {
+		out.Reset()
+		app = cli.App()
+		app.Writer = &out
+
+		p = make(map[string]*BasicPackageJSON)
+		p["api-1"] = NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.0.0").
+			Dependency("@scope/dep-b", "~2.1.0").
+			Dependency("lib-1", "github:someorg/lib-1").
+			Build()
+
+		p["lib-1"] = NewBasicPackageJSONBuilder().Dependency("dep-a", "^1.2.0").Build()
+
+		t, err = NewTestSpace(p)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(t.WriteManifest(NewBasicPackageJSONBuilder().
+			Dependency("dep-a", "^1.2.0").
+			Dependency("@scope/dep-b", "~2.1.0").
+			Build())).To(Succeed())
+
+		Expect(os.Symlink("../lib-1", filepath.Join(t.RootFolder, "node_modules", "lib-1"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(t.Destroy()).To(Succeed())
+	})
+
+	It("should succeed when every installed package satisfies the assembled package.json and each project", func() {
+		Expect(t.Install("dep-a", "1.4.0", "dep-c")).To(Succeed())
+		Expect(t.Install("@scope/dep-b", "2.1.3")).To(Succeed())
+		Expect(t.Install("dep-c", "0.1.0")).To(Succeed())
+
+		args := []string{"triforce", "verify-install", t.RootFolder}
+		Expect(app.Run(args)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("verified 4 installed package(s): 0 missing, 0 out of range, 0 extraneous"))
+	})
+
+	It("should report missing, out of range and extraneous packages", func() {
+		Expect(t.Install("dep-a", "1.1.0")).To(Succeed())
+		Expect(t.Install("dep-d", "3.0.0")).To(Succeed())
+
+		args := []string{"triforce", "verify-install", t.RootFolder}
+		Expect(app.Run(args)).NotTo(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`missing package "@scope/dep-b" (version "~2.1.0" required by the assembled package.json)`))
+		Expect(out.String()).To(ContainSubstring(`out of range package "dep-a" (installed version "1.1.0" does not satisfy "^1.2.0" required by the assembled package.json)`))
+		Expect(out.String()).To(ContainSubstring(`out of range package "dep-a" (installed version "1.1.0" does not satisfy "^1.2.0" required by lib-1)`))
+		Expect(out.String()).To(ContainSubstring(`extraneous package "dep-d"`))
+		Expect(out.String()).To(ContainSubstring("1 missing, 2 out of range, 1 extraneous"))
+	})
+
+	It("should check the version installed in a project's own node_modules folder first", func() {
+		Expect(t.Install("dep-a", "1.4.0")).To(Succeed())
+		Expect(t.Install("@scope/dep-b", "2.1.3")).To(Succeed())
+		Expect(t.InstallInProject("api-1", "dep-a", "0.9.0")).To(Succeed())
+
+		args := []string{"triforce", "verify-install", t.RootFolder}
+		Expect(app.Run(args)).NotTo(Succeed())
+		Expect(out.String()).To(ContainSubstring(`out of range package "dep-a" (installed version "0.9.0" does not satisfy "^1.0.0" required by api-1)`))
+	})
+})